@@ -0,0 +1,255 @@
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ErrApprovalCode is returned by SubmitApprovalCode and Login2FA if the
+// approval code is rejected.
+var ErrApprovalCode = errors.New("messenger: incorrect approval code")
+
+// CheckpointType represents the type of a login checkpoint.
+type CheckpointType string
+
+// Known checkpoint types.
+const (
+	// CheckpointApprovals requires a login approval code, such as one
+	// generated by a TOTP app or sent by SMS. Use SubmitApprovalCode to
+	// pass the checkpoint.
+	CheckpointApprovals CheckpointType = "login_approvals"
+	// CheckpointUnknown is any other checkpoint, which must be passed
+	// manually in a browser.
+	CheckpointUnknown CheckpointType = "unknown"
+)
+
+const (
+	defaultCheckpointURL = "https://www.facebook.com/checkpoint/"
+	maxCheckpointSteps   = 5
+)
+
+var (
+	approvalsCodeInput = []byte(`name="approvals_code"`)
+	saveDeviceInput    = []byte(`name="name_action_selected"`)
+)
+
+// CheckpointError is returned when logging in or connecting to chat if
+// Facebook requires the session to pass a checkpoint. Use errors.Is with
+// ErrLoginCheckpoint to check for any checkpoint, as the error is no longer
+// equal to ErrLoginCheckpoint itself, or errors.As to retrieve its type.
+type CheckpointError struct {
+	Type CheckpointType
+	URL  string
+}
+
+// Error returns the checkpoint error message.
+func (c CheckpointError) Error() string {
+	return ErrLoginCheckpoint.Error() + " (" + string(c.Type) + ")"
+}
+
+// Is returns whether the target is ErrLoginCheckpoint.
+func (c CheckpointError) Is(target error) bool {
+	return target == ErrLoginCheckpoint
+}
+
+// Unwrap returns ErrLoginCheckpoint.
+func (c CheckpointError) Unwrap() error {
+	return ErrLoginCheckpoint
+}
+
+func classifyCheckpoint(data []byte) CheckpointType {
+	if bytes.Contains(data, approvalsCodeInput) {
+		return CheckpointApprovals
+	}
+
+	return CheckpointUnknown
+}
+
+// Login2FA logs the session in to a Facebook account that has login
+// approvals enabled. If an approval code is required, codeFunc is called to
+// retrieve it.
+func (s *Session) Login2FA(email, password string,
+	codeFunc func() (string, error)) error {
+	err := s.Login(email, password)
+	cpErr, ok := err.(CheckpointError)
+	if !ok || cpErr.Type != CheckpointApprovals {
+		return err
+	}
+
+	code, err := codeFunc()
+	if err != nil {
+		return err
+	}
+
+	return s.SubmitApprovalCode(context.Background(), code)
+}
+
+// SubmitApprovalCode submits a login approval code to pass a checkpoint
+// returned by Login, and saves the browser so that future logins don't
+// require a code.
+func (s *Session) SubmitApprovalCode(ctx context.Context, code string) error {
+	checkpointURL := s.checkpointURL
+	if checkpointURL == "" {
+		checkpointURL = defaultCheckpointURL
+	}
+
+	data, err := s.getCheckpoint(ctx, checkpointURL)
+	if err != nil {
+		return err
+	}
+
+	codeSubmitted := false
+	for i := 0; i < maxCheckpointSteps; i++ {
+		action, form, err := parseCheckpointForm(data, checkpointURL)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Contains(data, approvalsCodeInput) {
+			if codeSubmitted {
+				return ErrApprovalCode
+			}
+
+			form.Set("approvals_code", code)
+			form.Set("submit[Submit Code]", "Submit Code")
+			codeSubmitted = true
+		} else if bytes.Contains(data, saveDeviceInput) {
+			form.Set("name_action_selected", "save_device")
+			form.Set("submit[Continue]", "Continue")
+		} else {
+			form.Set("submit[Continue]", "Continue")
+		}
+
+		redirURL, body, err := s.postCheckpoint(ctx, action, form)
+		if err != nil {
+			return err
+		}
+
+		if redirURL == "" {
+			data = body
+			continue
+		}
+
+		if !strings.Contains(redirURL, "https://www.facebook.com/checkpoint") {
+			s.checkpointURL = ""
+			return handleLoginRedirectURL(redirURL)
+		}
+
+		checkpointURL = redirURL
+		data, err = s.getCheckpoint(ctx, checkpointURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.checkpointURL = checkpointURL
+	return CheckpointError{Type: classifyCheckpoint(data), URL: checkpointURL}
+}
+
+func (s *Session) getCheckpoint(ctx context.Context, checkpointURL string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, checkpointURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = s.defaultHeader()
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// postCheckpoint submits a checkpoint form, and returns either the URL the
+// server redirected to, or the body of the response if there was no
+// redirect.
+func (s *Session) postCheckpoint(ctx context.Context, action string,
+	form url.Values) (string, []byte, error) {
+	req, err := http.NewRequest(http.MethodPost, action,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", nil, err
+	}
+
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		urlErr, ok := err.(*url.Error)
+		if !ok || urlErr.Err != errNoRedirects {
+			return "", nil, err
+		}
+
+		redirURL, err := resp.Location()
+		if err != nil {
+			return "", nil, err
+		}
+
+		return redirURL.String(), nil, nil
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	return "", data, err
+}
+
+// parseCheckpointForm returns the absolute action URL and the hidden fields
+// of the checkpoint form in the page. Submit buttons are excluded as only the
+// button being pressed should be sent.
+func parseCheckpointForm(data []byte, pageURL string) (string, url.Values, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, err
+	}
+
+	formSel := doc.Find("form.checkpoint").First()
+	if formSel.Length() == 0 {
+		return "", nil, ParseError{"could not find checkpoint form"}
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	action, _ := formSel.Attr("action")
+	actionURL, err := base.Parse(action)
+	if err != nil {
+		return "", nil, err
+	}
+
+	form := make(url.Values)
+	formSel.Find("input").Each(func(i int, s *goquery.Selection) {
+		name, found := s.Attr("name")
+		if !found || strings.HasPrefix(name, "submit[") {
+			return
+		}
+
+		if inputType, _ := s.Attr("type"); inputType == "radio" ||
+			inputType == "checkbox" {
+			if _, checked := s.Attr("checked"); !checked {
+				return
+			}
+		}
+
+		value, _ := s.Attr("value")
+		form.Set(name, value)
+	})
+
+	return actionURL.String(), form, nil
+}
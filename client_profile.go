@@ -0,0 +1,97 @@
+package messenger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ClientProfile represents the browser that the session presents itself as
+// to Facebook when logging in and making requests.
+type ClientProfile struct {
+	UserAgent string
+	// Locale is the Facebook locale, such as "en_US".
+	Locale       string
+	ScreenWidth  int
+	ScreenHeight int
+	Timezone     *time.Location
+}
+
+// DefaultClientProfile returns the client profile used by sessions unless
+// one is set with SetClientProfile.
+func DefaultClientProfile() ClientProfile {
+	return ClientProfile{
+		UserAgent:    userAgent,
+		Locale:       "en_US",
+		ScreenWidth:  1440,
+		ScreenHeight: 900,
+		Timezone:     time.Local,
+	}
+}
+
+// SetClientProfile sets the client profile of the session. Empty fields of
+// the profile are set to those of DefaultClientProfile. It should be set
+// before logging in.
+func (s *Session) SetClientProfile(profile ClientProfile) {
+	defaults := DefaultClientProfile()
+
+	if profile.UserAgent == "" {
+		profile.UserAgent = defaults.UserAgent
+	}
+	if profile.Locale == "" {
+		profile.Locale = defaults.Locale
+	}
+	if profile.ScreenWidth <= 0 || profile.ScreenHeight <= 0 {
+		profile.ScreenWidth = defaults.ScreenWidth
+		profile.ScreenHeight = defaults.ScreenHeight
+	}
+	if profile.Timezone == nil {
+		profile.Timezone = defaults.Timezone
+	}
+
+	s.profile = profile
+}
+
+type screenDimensions struct {
+	Width           int `json:"w"`
+	Height          int `json:"h"`
+	AvailableWidth  int `json:"aw"`
+	AvailableHeight int `json:"ah"`
+	ColorDepth      int `json:"c"`
+}
+
+// loginDimensions returns the encoded screen dimensions sent with the
+// login form.
+func (p ClientProfile) loginDimensions() string {
+	res, err := json.Marshal(screenDimensions{
+		Width:           p.ScreenWidth,
+		Height:          p.ScreenHeight,
+		AvailableWidth:  p.ScreenWidth,
+		AvailableHeight: p.ScreenHeight,
+		ColorDepth:      24,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(res)
+}
+
+// timezoneOffset returns the timezone offset in minutes as returned by
+// JavaScript's Date.getTimezoneOffset.
+func (p ClientProfile) timezoneOffset() int {
+	_, offset := time.Now().In(p.Timezone).Zone()
+	return -offset / 60
+}
+
+// acceptLanguage returns the Accept-Language header value for the locale.
+func (p ClientProfile) acceptLanguage() string {
+	tag := strings.Replace(p.Locale, "_", "-", -1)
+	lang := strings.SplitN(tag, "-", 2)[0]
+	if lang == tag {
+		return tag
+	}
+
+	return tag + "," + lang + ";q=0.9"
+}
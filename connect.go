@@ -57,12 +57,23 @@ func generateAccessibilityCookie() string {
 // ConnectToChat connects the session to chat after you've successfully
 // logged in.
 func (s *Session) ConnectToChat() error {
-	err := s.populateMeta()
+	s.setConnState(StateConnecting)
+
+	err := s.connectToChat()
 	if err != nil {
+		s.setConnState(StateClosed)
 		return err
 	}
 
-	s.l.form = s.newPullForm()
+	s.setConnState(StateConnected)
+	return nil
+}
+
+func (s *Session) connectToChat() error {
+	err := s.populateMeta()
+	if err != nil {
+		return err
+	}
 
 	err = s.requestReconnect()
 	if err != nil {
@@ -89,7 +100,7 @@ func (s *Session) ConnectToChat() error {
 
 func (s *Session) requestReconnect() error {
 	req, _ := http.NewRequest(http.MethodGet, reconnectURL, nil)
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	resp, err := s.doRequest(req)
 	if err != nil {
@@ -113,7 +124,7 @@ func (s *Session) connectToStage1() error {
 
 	defer resp.Body.Close()
 
-	respInfo, err := parseResponse(resp.Body)
+	respInfo, err := s.parseResponse(resp.Body)
 	if err != nil {
 		return err
 	}
@@ -122,6 +133,11 @@ func (s *Session) connectToStage1() error {
 		return ParseError{"non t: \"lb\" response from chat server"}
 	}
 
+	s.l.stateMutex.Lock()
+	defer s.l.stateMutex.Unlock()
+
+	s.l.form = s.newPullForm()
+	s.applyResume()
 	s.l.form.stickyPool = respInfo.Sticky.Pool
 	s.l.form.stickyToken = respInfo.Sticky.Token
 
@@ -153,7 +169,7 @@ func (s *Session) createStage1Request() (*http.Request, error) {
 		},
 		{
 			Name:   "locale",
-			Value:  "en_US",
+			Value:  s.profile.Locale,
 			Domain: ".facebook.com",
 			Secure: true,
 		},
@@ -171,7 +187,7 @@ func (s *Session) createStage1Request() (*http.Request, error) {
 
 	req, _ := http.NewRequest(http.MethodGet,
 		chatURL+form.form().Encode(), nil)
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	return req, nil
 }
@@ -179,7 +195,7 @@ func (s *Session) createStage1Request() (*http.Request, error) {
 func (s *Session) connectToStage2() error {
 	req, _ := http.NewRequest(http.MethodGet,
 		chatURL+s.l.form.form().Encode(), nil)
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	resp, err := s.doRequest(req)
 	if err != nil {
@@ -200,7 +216,7 @@ func (s *Session) connectToStage3() error {
 
 	req, _ := http.NewRequest(http.MethodPost, threadSyncURL,
 		strings.NewReader(form.Encode()))
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	resp, err := s.doRequest(req)
 	if err != nil {
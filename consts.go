@@ -9,21 +9,33 @@ import (
 )
 
 const (
-	facebookURL    = "https://www.facebook.com/"
-	facebookOrigin = "https://www.facebook.com"
-	loginURL       = "https://www.facebook.com/login.php?login_attempt=1&lwv=110"
-	chatURL        = "https://0-edge-chat.facebook.com/pull?"
-	threadSyncURL  = "https://www.facebook.com/ajax/mercury/thread_sync.php"
-	reconnectURL   = "https://www.facebook.com/ajax/presence/reconnect.php?reason=6"
-	readStatusURL  = "https://www.facebook.com/ajax/mercury/change_read_status.php"
-	sendMessageURL = "https://www.facebook.com/messaging/send/?dpr=2"
-	typingURL      = "https://www.facebook.com/ajax/messaging/typ.php"
-	syncURL        = "https://www.facebook.com/notifications/sync/?"
-	profileURL     = "https://www.facebook.com/chat/user_info/?dpr=2"
-	allProfileURL  = "https://www.facebook.com/chat/user_info_all"
-	userAgent      = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_10_2) AppleWebKit/600.3.18 (KHTML, like Gecko) Version/8.0.3 Safari/600.3.18"
-	formURLEncoded = "application/x-www-form-urlencoded"
-	loggedOutError = 1357001
+	facebookURL       = "https://www.facebook.com/"
+	facebookOrigin    = "https://www.facebook.com"
+	loginURL          = "https://www.facebook.com/login.php?login_attempt=1&lwv=110"
+	chatURL           = "https://0-edge-chat.facebook.com/pull?"
+	threadSyncURL     = "https://www.facebook.com/ajax/mercury/thread_sync.php"
+	reconnectURL      = "https://www.facebook.com/ajax/presence/reconnect.php?reason=6"
+	readStatusURL     = "https://www.facebook.com/ajax/mercury/change_read_status.php"
+	sendMessageURL    = "https://www.facebook.com/messaging/send/?dpr=2"
+	typingURL         = "https://www.facebook.com/ajax/messaging/typ.php"
+	syncURL           = "https://www.facebook.com/notifications/sync/?"
+	profileURL        = "https://www.facebook.com/chat/user_info/?dpr=2"
+	allProfileURL     = "https://www.facebook.com/chat/user_info_all"
+	graphURL          = "https://graph.facebook.com/"
+	searchUsersURL    = "https://www.facebook.com/ajax/typeahead/search.php?"
+	searchThreadsURL  = "https://www.facebook.com/ajax/mercury/search_threads.php"
+	threadInfoURL     = "https://www.facebook.com/ajax/mercury/thread_info.php"
+	friendRequestsURL = "https://www.facebook.com/friends/requests/"
+	respondFriendURL  = "https://www.facebook.com/requests/friends/ajax/"
+	addFriendURL      = "https://www.facebook.com/ajax/add_friend/action.php"
+	cancelFriendURL   = "https://www.facebook.com/ajax/friends/requests/cancel.php"
+	threadListURL     = "https://www.facebook.com/ajax/mercury/threadlist_info.php"
+	moveThreadURL     = "https://www.facebook.com/ajax/mercury/move_thread.php"
+	logoutURL         = "https://www.facebook.com/logout.php"
+	logoutMenuURL     = "https://www.facebook.com/bluebar/modern_settings_menu/?help_type=364455653583099&show_contextual_help=1"
+	userAgent         = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_10_2) AppleWebKit/600.3.18 (KHTML, like Gecko) Version/8.0.3 Safari/600.3.18"
+	formURLEncoded    = "application/x-www-form-urlencoded"
+	loggedOutError    = 1357001
 )
 
 var (
@@ -32,9 +44,10 @@ var (
 	edgeURL, _     = url.Parse("https://0-edge-chat.facebook.com")
 )
 
-func defaultHeader() http.Header {
+func (s *Session) defaultHeader() http.Header {
 	header := make(http.Header)
-	header.Set("User-Agent", userAgent)
+	header.Set("User-Agent", s.profile.UserAgent)
+	header.Set("Accept-Language", s.profile.acceptLanguage())
 	header.Set("Origin", facebookOrigin)
 	header.Set("Referer", facebookURL)
 	return header
@@ -0,0 +1,112 @@
+package messenger
+
+const defaultDedupSize = 100
+
+// maxDedupThreads is the maximum number of threads whose message IDs are
+// remembered. The least recently active thread is forgotten when it's
+// exceeded.
+const maxDedupThreads = 1000
+
+// processedThread represents the recently seen message IDs of a thread.
+type processedThread struct {
+	ids []string
+	// lastUsed is the value of the processed clock when a message was
+	// last seen in the thread.
+	lastUsed uint64
+}
+
+// SetDeduplicationSize sets the number of recently seen message IDs that are
+// remembered per thread to suppress duplicate messages, which can occur after
+// a reconnect or a full reload. A size of 0 or less disables deduplication.
+// The default size is 100.
+func (s *Session) SetDeduplicationSize(size int) {
+	s.l.processedMutex.Lock()
+	defer s.l.processedMutex.Unlock()
+
+	s.l.dedupSize = size
+	for threadID, thread := range s.l.processedThreadMessages {
+		if size <= 0 {
+			delete(s.l.processedThreadMessages, threadID)
+		} else if len(thread.ids) > size {
+			thread.ids = thread.ids[len(thread.ids)-size:]
+		}
+	}
+}
+
+// DuplicatesDropped returns the number of duplicate messages that have been
+// suppressed by the session.
+func (s *Session) DuplicatesDropped() int64 {
+	s.l.processedMutex.Lock()
+	defer s.l.processedMutex.Unlock()
+	return s.l.duplicatesDropped
+}
+
+// markProcessed records the message as seen in the thread and returns
+// whether it had already been seen before. The most recently seen message IDs
+// are kept at the end of the thread's list.
+func (s *Session) markProcessed(threadID, messageID string) bool {
+	if messageID == "" {
+		return false
+	}
+
+	duplicate := s.recordProcessed(threadID, messageID)
+	if duplicate {
+		s.metrics.DuplicateDropped()
+	}
+
+	return duplicate
+}
+
+func (s *Session) recordProcessed(threadID, messageID string) bool {
+	s.l.processedMutex.Lock()
+	defer s.l.processedMutex.Unlock()
+
+	if s.l.dedupSize <= 0 {
+		return false
+	}
+
+	s.l.processedClock++
+
+	thread, ok := s.l.processedThreadMessages[threadID]
+	if !ok {
+		if len(s.l.processedThreadMessages) >= maxDedupThreads {
+			s.evictProcessedThread()
+		}
+
+		thread = new(processedThread)
+		s.l.processedThreadMessages[threadID] = thread
+	}
+
+	thread.lastUsed = s.l.processedClock
+
+	for i, id := range thread.ids {
+		if id == messageID {
+			copy(thread.ids[i:], thread.ids[i+1:])
+			thread.ids[len(thread.ids)-1] = messageID
+			s.l.duplicatesDropped++
+			return true
+		}
+	}
+
+	thread.ids = append(thread.ids, messageID)
+	if len(thread.ids) > s.l.dedupSize {
+		thread.ids = thread.ids[len(thread.ids)-s.l.dedupSize:]
+	}
+
+	return false
+}
+
+// evictProcessedThread forgets the least recently active thread, and must be
+// called with the processed mutex held.
+func (s *Session) evictProcessedThread() {
+	var oldestID string
+	var oldest *processedThread
+	for threadID, thread := range s.l.processedThreadMessages {
+		if oldest == nil || thread.lastUsed < oldest.lastUsed {
+			oldestID = threadID
+			oldest = thread
+		}
+	}
+
+	delete(s.l.processedThreadMessages, oldestID)
+}
@@ -0,0 +1,87 @@
+package messenger
+
+import (
+	"strconv"
+	"testing"
+)
+
+// dedupMetrics counts dropped duplicates, checking that the processed mutex
+// isn't held when it's called.
+type dedupMetrics struct {
+	nopMetrics
+	s       *Session
+	dropped int
+}
+
+func (m *dedupMetrics) DuplicateDropped() {
+	m.s.l.processedMutex.Lock()
+	m.s.l.processedMutex.Unlock()
+	m.dropped++
+}
+
+func TestMarkProcessed(t *testing.T) {
+	s := NewSession()
+	metrics := &dedupMetrics{s: s}
+	s.SetMetrics(metrics)
+	s.SetDeduplicationSize(2)
+
+	tests := []struct {
+		threadID  string
+		messageID string
+		duplicate bool
+	}{
+		{"1", "a", false},
+		{"1", "a", true},
+		{"2", "a", false},
+		{"1", "", false},
+		{"1", "", false},
+		{"1", "b", false},
+		{"1", "c", false},
+		// a has been pushed out of thread 1 by b and c.
+		{"1", "a", false},
+		{"1", "c", true},
+	}
+
+	for i, test := range tests {
+		duplicate := s.markProcessed(test.threadID, test.messageID)
+		if duplicate != test.duplicate {
+			t.Errorf("%d: markProcessed(%q, %q) = %v, want %v", i,
+				test.threadID, test.messageID, duplicate, test.duplicate)
+		}
+	}
+
+	if s.DuplicatesDropped() != 2 || metrics.dropped != 2 {
+		t.Errorf("expected 2 duplicates dropped, got %d and %d metrics",
+			s.DuplicatesDropped(), metrics.dropped)
+	}
+
+	s.SetDeduplicationSize(0)
+	if s.markProcessed("1", "c") {
+		t.Error("duplicate reported with deduplication disabled")
+	}
+}
+
+func TestMarkProcessedEvictsThreads(t *testing.T) {
+	s := NewSession()
+
+	s.markProcessed("active", "a")
+	for i := 0; i < maxDedupThreads+10; i++ {
+		s.markProcessed(strconv.Itoa(i), "a")
+		if i%100 == 0 {
+			s.markProcessed("active", "b")
+		}
+	}
+
+	if len(s.l.processedThreadMessages) > maxDedupThreads {
+		t.Errorf("tracking %d threads, want at most %d",
+			len(s.l.processedThreadMessages), maxDedupThreads)
+	}
+
+	if !s.markProcessed("active", "a") {
+		t.Error("recently active thread was evicted")
+	}
+
+	if s.markProcessed("0", "a") {
+		t.Error("least recently active thread wasn't evicted")
+	}
+}
@@ -3,44 +3,129 @@ package messenger
 import (
 	"bytes"
 	"encoding/gob"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 )
 
+// sessionDumpVersion is the version of the session dump format produced by
+// DumpSession. It must be incremented whenever the format changes in a way
+// that older versions of RestoreSession can't read.
+const sessionDumpVersion = 1
+
+// sessionDump is the legacy gob encoded session dump, which only contains
+// cookies.
 type sessionDump struct {
 	FBCookies   []*http.Cookie
 	EdgeCookies []*http.Cookie
 }
 
-// DumpSession dumps the session (i.e. cookies) and returns it as a []byte.
+type jsonSessionDump struct {
+	Version  int           `json:"version"`
+	UserID   string        `json:"user_id"`
+	ClientID string        `json:"client_id"`
+	Meta     dumpMeta      `json:"meta"`
+	Listener ListenerState `json:"listener"`
+	Cookies  dumpCookies   `json:"cookies"`
+}
+
+type dumpMeta struct {
+	Req      int64  `json:"req"`
+	Revision string `json:"revision"`
+	DTSG     string `json:"fb_dtsg"`
+	TTStamp  string `json:"ttstamp"`
+}
+
+type dumpCookies struct {
+	Facebook []dumpCookie `json:"facebook"`
+	Edge     []dumpCookie `json:"edge"`
+}
+
+type dumpCookie struct {
+	Name    string     `json:"name"`
+	Value   string     `json:"value"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+func (j *sessionJar) toDumpCookies(cookies []*http.Cookie) []dumpCookie {
+	result := make([]dumpCookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		dumped := dumpCookie{
+			Name:  cookie.Name,
+			Value: cookie.Value,
+		}
+
+		if expires := j.expiry(cookie.Name); !expires.IsZero() {
+			dumped.Expires = &expires
+		}
+
+		result = append(result, dumped)
+	}
+
+	return result
+}
+
+func fromDumpCookies(cookies []dumpCookie) []*http.Cookie {
+	result := make([]*http.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		restored := &http.Cookie{
+			Name:  cookie.Name,
+			Value: cookie.Value,
+		}
+
+		if cookie.Expires != nil {
+			restored.Expires = *cookie.Expires
+		}
+
+		result = append(result, restored)
+	}
+
+	return result
+}
+
+// DumpSession dumps the session and returns it as a []byte. The dump is
+// versioned JSON containing the cookies, user ID, client ID, page tokens and
+// listener state of the session.
 // Note that if you restore the session, you may not need to login, but you
 // must reconnect to chat.
 func (s *Session) DumpSession() ([]byte, error) {
 	s.requestMutex.RLock()
 	defer s.requestMutex.RUnlock()
 
-	fbCookies := s.client.Jar.Cookies(fbURL)
-	edgeCookies := s.client.Jar.Cookies(edgeURL)
-
-	buf := new(bytes.Buffer)
-	enc := gob.NewEncoder(buf)
-	err := enc.Encode(sessionDump{
-		FBCookies:   fbCookies,
-		EdgeCookies: edgeCookies,
+	return json.Marshal(jsonSessionDump{
+		Version:  sessionDumpVersion,
+		UserID:   s.userID,
+		ClientID: s.clientID,
+		Meta: dumpMeta{
+			Req:      s.meta.req,
+			Revision: s.meta.revision,
+			DTSG:     s.meta.dtsg,
+			TTStamp:  s.meta.ttstamp,
+		},
+		Listener: s.ListenerState(),
+		Cookies: dumpCookies{
+			Facebook: s.jar.toDumpCookies(s.client.Jar.Cookies(fbURL)),
+			Edge:     s.jar.toDumpCookies(s.client.Jar.Cookies(edgeURL)),
+		},
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return buf.Bytes(), nil
 }
 
-// RestoreSession restores the session (i.e. cookies) stored as a []byte
-// back into the session. Note that you may not need to login again, but you
-// must reconnect to chat.
+// RestoreSession restores the session stored as a []byte back into the
+// session. Both the JSON format produced by DumpSession and the legacy gob
+// format containing only cookies are accepted. Note that you may not need to
+// login again, but you must reconnect to chat.
 func (s *Session) RestoreSession(data []byte) error {
+	s.setLoggingOut(false)
+
 	s.requestMutex.Lock()
 	defer s.requestMutex.Unlock()
 
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		return s.restoreJSONSession(data)
+	}
+
 	buf := bytes.NewReader(data)
 	dec := gob.NewDecoder(buf)
 	restoredSession := sessionDump{}
@@ -55,6 +140,42 @@ func (s *Session) RestoreSession(data []byte) error {
 	return nil
 }
 
+func (s *Session) restoreJSONSession(data []byte) error {
+	var restoredSession jsonSessionDump
+	err := json.Unmarshal(data, &restoredSession)
+	if err != nil {
+		return err
+	}
+
+	if restoredSession.Version < 1 ||
+		restoredSession.Version > sessionDumpVersion {
+		return ParseError{"unsupported session dump version " +
+			strconv.Itoa(restoredSession.Version)}
+	}
+
+	s.client.Jar.SetCookies(fbURL,
+		fromDumpCookies(restoredSession.Cookies.Facebook))
+	s.client.Jar.SetCookies(edgeURL,
+		fromDumpCookies(restoredSession.Cookies.Edge))
+
+	s.userID = restoredSession.UserID
+	s.clientID = restoredSession.ClientID
+
+	if restoredSession.Meta.DTSG != "" {
+		s.meta.req = restoredSession.Meta.Req
+		s.meta.revision = restoredSession.Meta.Revision
+		s.meta.dtsg = restoredSession.Meta.DTSG
+		s.meta.ttstamp = restoredSession.Meta.TTStamp
+	}
+
+	listener := restoredSession.Listener
+	if listener.Seq != 0 || listener.LastTimestamp != 0 {
+		s.ResumeListener(listener)
+	}
+
+	return nil
+}
+
 func init() {
 	gob.Register(sessionDump{})
 }
@@ -0,0 +1,194 @@
+package messenger
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted session dumps start with a header consisting of the magic bytes,
+// the key derivation function used, and the scrypt salt if a passphrase was
+// used. The header is followed by the AES-GCM nonce and the sealed session
+// dump, which authenticates the header as additional data.
+var encryptedDumpMagic = []byte("MSE1")
+
+const (
+	kdfNone   byte = 0
+	kdfScrypt byte = 1
+
+	scryptSaltSize = 16
+	scryptN        = 1 << 15
+	scryptR        = 8
+	scryptP        = 1
+	scryptKeySize  = 32
+)
+
+// DumpSessionEncrypted dumps the session like DumpSession, and encrypts it
+// with AES-GCM using the given key, which must be 16, 24 or 32 bytes long.
+func (s *Session) DumpSessionEncrypted(key []byte) ([]byte, error) {
+	data, err := s.DumpSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptDump(key, data)
+}
+
+// encryptDump encrypts the session dump with the key.
+func encryptDump(key, data []byte) ([]byte, error) {
+	header := append(append([]byte{}, encryptedDumpMagic...), kdfNone)
+	return sealSession(header, key, data)
+}
+
+// RestoreSessionEncrypted restores a session dumped with
+// DumpSessionEncrypted using the same key. A DecryptError is returned if the
+// key is incorrect or the data has been tampered with.
+func (s *Session) RestoreSessionEncrypted(key []byte, data []byte) error {
+	plaintext, err := decryptDump(key, data)
+	if err != nil {
+		return err
+	}
+
+	return s.RestoreSession(plaintext)
+}
+
+// decryptDump decrypts a session dump encrypted with the key.
+func decryptDump(key, data []byte) ([]byte, error) {
+	kdf, rest, err := parseEncryptedHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if kdf == kdfScrypt {
+		return nil, DecryptError{"session dump is encrypted with a passphrase"}
+	}
+
+	return openSession(data[:len(data)-len(rest)], key, rest)
+}
+
+// DumpSessionWithPassphrase dumps the session like DumpSessionEncrypted,
+// deriving the key from the passphrase with scrypt and a random salt.
+func (s *Session) DumpSessionWithPassphrase(passphrase string) ([]byte, error) {
+	data, err := s.DumpSession()
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, scryptSaltSize)
+	_, err = io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := passphraseKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append([]byte{}, encryptedDumpMagic...), kdfScrypt)
+	header = append(header, salt...)
+	return sealSession(header, key, data)
+}
+
+// RestoreSessionWithPassphrase restores a session dumped with
+// DumpSessionWithPassphrase using the same passphrase. A DecryptError is
+// returned if the passphrase is incorrect or the data has been tampered with.
+func (s *Session) RestoreSessionWithPassphrase(passphrase string,
+	data []byte) error {
+	kdf, rest, err := parseEncryptedHeader(data)
+	if err != nil {
+		return err
+	}
+
+	if kdf == kdfNone {
+		return DecryptError{"session dump is not encrypted with a passphrase"}
+	}
+
+	if len(rest) < scryptSaltSize {
+		return DecryptError{"encrypted session dump is truncated"}
+	}
+
+	key, err := passphraseKey(passphrase, rest[:scryptSaltSize])
+	if err != nil {
+		return err
+	}
+
+	rest = rest[scryptSaltSize:]
+	plaintext, err := openSession(data[:len(data)-len(rest)], key, rest)
+	if err != nil {
+		return err
+	}
+
+	return s.RestoreSession(plaintext)
+}
+
+func passphraseKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP,
+		scryptKeySize)
+}
+
+func parseEncryptedHeader(data []byte) (byte, []byte, error) {
+	if len(data) <= len(encryptedDumpMagic) ||
+		!bytes.HasPrefix(data, encryptedDumpMagic) {
+		return 0, nil, DecryptError{"not an encrypted session dump"}
+	}
+
+	kdf := data[len(encryptedDumpMagic)]
+	if kdf != kdfNone && kdf != kdfScrypt {
+		return 0, nil, DecryptError{"unknown key derivation in encrypted " +
+			"session dump"}
+	}
+
+	return kdf, data[len(encryptedDumpMagic)+1:], nil
+}
+
+func sealSession(header, key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	result := append(header, nonce...)
+	return gcm.Seal(result, nonce, plaintext, header), nil
+}
+
+// openSession decrypts the sealed data, which consists of the nonce followed
+// by the ciphertext, authenticating the header.
+func openSession(header, key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, DecryptError{"encrypted session dump is truncated"}
+	}
+
+	nonce := sealed[:gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, sealed[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, DecryptError{"incorrect key or session dump has been " +
+			"tampered with"}
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
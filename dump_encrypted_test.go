@@ -0,0 +1,133 @@
+package messenger
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func newDumpSession() *Session {
+	s := NewSession()
+	s.client.Jar.SetCookies(fbURL, []*http.Cookie{
+		{Name: "c_user", Value: "1000", Domain: ".facebook.com"},
+		{Name: "xs", Value: "secret", Domain: ".facebook.com"},
+	})
+
+	return s
+}
+
+func hasCookie(s *Session, name, value string) bool {
+	for _, cookie := range s.client.Jar.Cookies(fbURL) {
+		if cookie.Name == name && cookie.Value == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestSessionEncryptedRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	data, err := newDumpSession().DumpSessionEncrypted(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte("secret")) {
+		t.Error("encrypted dump contains plaintext cookie")
+	}
+
+	restored := NewSession()
+	err = restored.RestoreSessionEncrypted(key, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasCookie(restored, "xs", "secret") {
+		t.Error("cookie wasn't restored")
+	}
+}
+
+func TestSessionPassphraseRoundTrip(t *testing.T) {
+	data, err := newDumpSession().DumpSessionWithPassphrase("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewSession()
+	err = restored.RestoreSessionWithPassphrase("hunter2", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasCookie(restored, "xs", "secret") {
+		t.Error("cookie wasn't restored")
+	}
+
+	err = NewSession().RestoreSessionWithPassphrase("hunter3", data)
+	if _, ok := err.(DecryptError); !ok {
+		t.Errorf("expected DecryptError for wrong passphrase, got %v", err)
+	}
+
+	err = NewSession().RestoreSessionEncrypted(make([]byte, 32), data)
+	if _, ok := err.(DecryptError); !ok {
+		t.Errorf("expected DecryptError for passphrase dump, got %v", err)
+	}
+}
+
+func TestSessionEncryptedErrors(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	data, err := newDumpSession().DumpSessionEncrypted(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-1] ^= 1
+
+	tamperedHeader := append([]byte(nil), data...)
+	copy(tamperedHeader, "MSE2")
+
+	unknownKDF := append([]byte(nil), data...)
+	unknownKDF[len(encryptedDumpMagic)] = 9
+
+	tests := []struct {
+		name string
+		key  []byte
+		data []byte
+	}{
+		{"wrong key", bytes.Repeat([]byte{8}, 32), data},
+		{"tampered", key, tampered},
+		{"tampered header", key, tamperedHeader},
+		{"unknown key derivation", key, unknownKDF},
+		{"truncated nonce", key, data[:len(encryptedDumpMagic)+4]},
+		{"truncated ciphertext", key, data[:len(data)-4]},
+		{"magic only", key, encryptedDumpMagic},
+		{"empty", key, nil},
+		{"plaintext", key, []byte(`{"version":1}`)},
+	}
+
+	for _, test := range tests {
+		err := NewSession().RestoreSessionEncrypted(test.key, test.data)
+		if _, ok := err.(DecryptError); !ok {
+			t.Errorf("%s: expected DecryptError, got %v", test.name, err)
+		}
+	}
+
+	err = NewSession().RestoreSessionWithPassphrase("hunter2", data)
+	if _, ok := err.(DecryptError); !ok {
+		t.Errorf("expected DecryptError for key dump, got %v", err)
+	}
+
+	err = NewSession().RestoreSessionWithPassphrase("hunter2",
+		append(append([]byte(nil), encryptedDumpMagic...), kdfScrypt, 1, 2))
+	if _, ok := err.(DecryptError); !ok {
+		t.Errorf("expected DecryptError for truncated salt, got %v", err)
+	}
+
+	err = NewSession().RestoreSessionWithPassphrase("hunter2", unknownKDF)
+	if err == nil || err.Error() != "messenger: unknown key derivation in "+
+		"encrypted session dump" {
+		t.Errorf("unexpected error for unknown key derivation: %v", err)
+	}
+}
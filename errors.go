@@ -9,3 +9,15 @@ type ParseError struct {
 func (p ParseError) Error() string {
 	return "messenger: " + p.message
 }
+
+// DecryptError is returned if an encrypted session dump can't be decrypted,
+// either because the key is incorrect or because the dump has been
+// tampered with.
+type DecryptError struct {
+	message string
+}
+
+// Error returns the detailed decryption error message.
+func (d DecryptError) Error() string {
+	return "messenger: " + d.message
+}
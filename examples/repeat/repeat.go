@@ -1,18 +1,20 @@
 // This is an example of a Facebook messenger chat bot which repeats
 // messages it receives. It also persist sessions by saving sessions
-// to a file.
+// to an encrypted file using a session store.
 
 // The email and password used is taken from the environment variables
-// FBEMAIL and FBPASS.
+// FBEMAIL and FBPASS. The session file is encrypted with the key in the
+// environment variable FBSESSIONKEY, which is 64 hex characters that can be
+// generated with "openssl rand -hex 32".
 
 package main
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
-	"time"
 
 	"github.com/1lann/messenger"
 )
@@ -23,9 +25,22 @@ var s *messenger.Session
 
 func main() {
 	s = messenger.NewSession()
+
+	key, err := hex.DecodeString(os.Getenv("FBSESSIONKEY"))
+	if err != nil || len(key) != 32 {
+		fmt.Println("FBSESSIONKEY must be 64 hex characters")
+		os.Exit(1)
+	}
+
+	// The session is saved to the session file whenever its cookies change.
+	// The use of saved session prevents Facebook from flagging your bot as
+	// suspicious for having to re-log so often, and also makes it faster to
+	// test your bot as it doesn't need to log in again every time.
+	s.SetSessionStore(messenger.NewEncryptedStore(
+		messenger.NewFileStore(sessionFile), key))
 	login()
 
-	err := s.ConnectToChat()
+	err = s.ConnectToChat()
 	if err != nil {
 		fmt.Println("Failed to connect to chat:", err)
 		return
@@ -33,17 +48,6 @@ func main() {
 
 	fmt.Println("Connected to chat")
 
-	// Save the session file every minute. The use of saved session prevents
-	// Facebook from flagging your bot as suspicious for having to re-log so
-	// often, and also makes it faster to test your bot as it doesn't need to
-	// log in again every time.
-	go func() {
-		ticker := time.Tick(time.Minute)
-		for range ticker {
-			saveSession()
-		}
-	}()
-
 	s.OnMessage(func(msg *messenger.Message) {
 		fmt.Println("Received \"" + msg.Body + "\" from " + msg.FromUserID)
 
@@ -61,38 +65,35 @@ func main() {
 }
 
 func login() {
-	sessionData, err := ioutil.ReadFile(sessionFile)
-	if os.IsNotExist(err) {
+	err := s.LoadSession()
+	if err == messenger.ErrNoSession {
 		fmt.Println("No session file, logging in...")
-		err = s.Login(os.Getenv("FBEMAIL"), os.Getenv("FBPASS"))
-		if err != nil {
-			fmt.Println("Failed to login:", err)
-			os.Exit(1)
-		}
+		loginWithPassword()
 		return
 	}
 
-	err = s.RestoreSession(sessionData)
 	if err != nil {
 		log.Println("Failed to restore session, logging in...")
-		err = s.Login(os.Getenv("FBEMAIL"), os.Getenv("FBPASS"))
-		if err != nil {
-			fmt.Println("Failed to login:", err)
-			os.Exit(1)
-		}
+		loginWithPassword()
 		return
 	}
 }
 
-func saveSession() {
-	data, err := s.DumpSession()
-	if err != nil {
-		fmt.Println("Failed to save session:", err)
+func loginWithPassword() {
+	err := s.Login(os.Getenv("FBEMAIL"), os.Getenv("FBPASS"))
+	if err == nil {
 		return
 	}
 
-	err = ioutil.WriteFile(sessionFile, data, 0644)
-	if err != nil {
-		fmt.Println("Failed to write session to file:", err)
+	// Checkpoint errors carry the checkpoint's type and URL, so they must
+	// be checked with errors.Is or errors.As rather than compared with ==.
+	var cpErr messenger.CheckpointError
+	if errors.As(err, &cpErr) {
+		fmt.Println("Login requires passing a checkpoint in a browser:",
+			cpErr.URL)
+	} else {
+		fmt.Println("Failed to login:", err)
 	}
+
+	os.Exit(1)
 }
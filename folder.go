@@ -0,0 +1,102 @@
+package messenger
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Folder represents a folder that threads are listed in.
+type Folder string
+
+// Thread folders. Threads from people who aren't friends start in the
+// pending folder as message requests, and are moved to the inbox when the
+// request is accepted, or to the other folder when it's ignored.
+const (
+	FolderInbox   Folder = "inbox"
+	FolderPending Folder = "pending"
+	FolderOther   Folder = "other"
+)
+
+// isRequest returns whether threads in the folder are message requests.
+func (f Folder) isRequest() bool {
+	return f == FolderPending || f == FolderOther
+}
+
+type threadListResponse struct {
+	Payload struct {
+		Threads []mercuryThread `json:"threads"`
+	} `json:"payload"`
+	Error int `json:"error"`
+}
+
+// Threads returns up to limit threads in the folder, starting from offset,
+// ordered by most recently updated. Use FolderPending to list message
+// requests.
+func (s *Session) Threads(ctx context.Context, folder Folder, offset,
+	limit int) ([]ThreadInfo, error) {
+	form := make(url.Values)
+	form.Set("client", "mercury")
+	form.Set(string(folder)+"[offset]", strconv.Itoa(offset))
+	form.Set(string(folder)+"[limit]", strconv.Itoa(limit))
+	form = s.addFormMeta(form)
+
+	req, _ := http.NewRequest(http.MethodPost, threadListURL,
+		strings.NewReader(form.Encode()))
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var listResp threadListResponse
+	err = s.unmarshalPullData(resp.Body, &listResp)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.responseError(listResp.Error)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := make([]ThreadInfo, 0, len(listResp.Payload.Threads))
+	for _, thread := range listResp.Payload.Threads {
+		info := thread.info()
+		if info.Folder == "" {
+			info.Folder = folder
+		}
+
+		threads = append(threads, info)
+	}
+
+	return threads, nil
+}
+
+// AcceptMessageRequest accepts the message request of the thread, moving it
+// to the inbox.
+func (s *Session) AcceptMessageRequest(ctx context.Context, thread Thread) error {
+	return s.moveThread(ctx, thread, FolderInbox)
+}
+
+// IgnoreMessageRequest ignores the message request of the thread, moving it
+// to the other folder.
+func (s *Session) IgnoreMessageRequest(ctx context.Context, thread Thread) error {
+	return s.moveThread(ctx, thread, FolderOther)
+}
+
+func (s *Session) moveThread(ctx context.Context, thread Thread,
+	folder Folder) error {
+	form := make(url.Values)
+	form.Set("client", "mercury")
+	form.Set(string(folder)+"[0]", thread.ThreadID)
+
+	return s.postAction(ctx, moveThreadURL, form)
+}
@@ -0,0 +1,108 @@
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// FriendRequest represents a pending incoming friend request.
+type FriendRequest struct {
+	UserID string
+	Name   string
+}
+
+// friendRequestsSelector selects the container of the friend requests on
+// the friend requests page, which is present even if there are no requests.
+const friendRequestsSelector = "#friend_requests_section"
+
+// FriendRequests returns the pending incoming friend requests of the
+// session's account. A ParseError is returned if the friend requests
+// couldn't be found on the page, so that an unexpected page isn't mistaken
+// for having no requests.
+func (s *Session) FriendRequests(ctx context.Context) ([]FriendRequest, error) {
+	req, _ := http.NewRequest(http.MethodGet, friendRequestsURL, nil)
+	req.Header = s.defaultHeader()
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	container := doc.Find(friendRequestsSelector)
+	if container.Length() == 0 {
+		return nil, ParseError{"could not find friend requests in page"}
+	}
+
+	var requests []FriendRequest
+	container.Find(".friendRequestItem[data-id]").Each(func(i int, s *goquery.Selection) {
+		userID, _ := s.Attr("data-id")
+		requests = append(requests, FriendRequest{
+			UserID: userID,
+			Name:   strings.TrimSpace(s.Find(".title a").First().Text()),
+		})
+	})
+
+	return requests, nil
+}
+
+// AcceptFriendRequest accepts the pending friend request from the user.
+func (s *Session) AcceptFriendRequest(ctx context.Context, userID string) error {
+	return s.respondToFriendRequest(ctx, userID, "confirm")
+}
+
+// DeclineFriendRequest declines the pending friend request from the user.
+func (s *Session) DeclineFriendRequest(ctx context.Context, userID string) error {
+	return s.respondToFriendRequest(ctx, userID, "reject")
+}
+
+func (s *Session) respondToFriendRequest(ctx context.Context, userID,
+	action string) error {
+	form := make(url.Values)
+	form.Set("viewer_id", s.userID)
+	form.Set("frefs[0]", "jwl")
+	form.Set("floc", "friend_center_requests")
+	form.Set("ref", "/reqs.php")
+	form.Set("action", action)
+	form.Set("id", userID)
+
+	return s.postAction(ctx, respondFriendURL, form)
+}
+
+// SendFriendRequest sends a friend request to the user.
+func (s *Session) SendFriendRequest(ctx context.Context, userID string) error {
+	form := make(url.Values)
+	form.Set("to_friend", userID)
+	form.Set("action", "add_friend")
+	form.Set("how_found", "profile_button")
+	form.Set("ref_param", "none")
+
+	return s.postAction(ctx, addFriendURL, form)
+}
+
+// CancelFriendRequest cancels a friend request sent to the user.
+func (s *Session) CancelFriendRequest(ctx context.Context, userID string) error {
+	form := make(url.Values)
+	form.Set("friend", userID)
+	form.Set("cancel_ref", "profile")
+
+	return s.postAction(ctx, cancelFriendURL, form)
+}
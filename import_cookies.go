@@ -0,0 +1,165 @@
+package messenger
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CookieFormat represents the format of cookies exported from a browser.
+type CookieFormat int
+
+// Supported cookie export formats.
+const (
+	// CookieFormatNetscape is the tab separated Netscape cookies.txt format.
+	CookieFormatNetscape CookieFormat = iota
+	// CookieFormatJSON is the JSON array format used by common cookie
+	// export browser extensions, with fields such as "domain", "name",
+	// "value", "path" and "expirationDate".
+	CookieFormatJSON
+)
+
+type importedCookie struct {
+	Domain         string  `json:"domain"`
+	HostOnly       bool    `json:"hostOnly"`
+	Path           string  `json:"path"`
+	Secure         bool    `json:"secure"`
+	HTTPOnly       bool    `json:"httpOnly"`
+	ExpirationDate float64 `json:"expirationDate"`
+	Name           string  `json:"name"`
+	Value          string  `json:"value"`
+}
+
+// ImportCookies imports Facebook cookies exported from a browser into the
+// session, as an alternative to logging in with Login. Cookies for domains
+// other than facebook.com are ignored. You must connect to chat after
+// importing cookies.
+func (s *Session) ImportCookies(r io.Reader, format CookieFormat) error {
+	s.setLoggingOut(false)
+
+	var cookies []importedCookie
+	var err error
+
+	switch format {
+	case CookieFormatNetscape:
+		cookies, err = parseNetscapeCookies(r)
+	case CookieFormatJSON:
+		err = json.NewDecoder(r).Decode(&cookies)
+	default:
+		return ParseError{"unknown cookie format"}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.requestMutex.Lock()
+	defer s.requestMutex.Unlock()
+
+	now := time.Now()
+	userID := ""
+	var fbCookies, edgeCookies []*http.Cookie
+
+	for _, imported := range cookies {
+		domain := strings.TrimPrefix(imported.Domain, ".")
+		if domain != "facebook.com" && !strings.HasSuffix(domain, ".facebook.com") {
+			continue
+		}
+
+		cookie := &http.Cookie{
+			Name:     imported.Name,
+			Value:    imported.Value,
+			Path:     imported.Path,
+			Secure:   imported.Secure,
+			HttpOnly: imported.HTTPOnly,
+		}
+
+		if imported.ExpirationDate > 0 {
+			cookie.Expires = time.Unix(int64(imported.ExpirationDate), 0)
+			if cookie.Expires.Before(now) {
+				continue
+			}
+		}
+
+		if imported.Name == "c_user" {
+			userID = imported.Value
+		}
+
+		if !imported.HostOnly {
+			cookie.Domain = ".facebook.com"
+			fbCookies = append(fbCookies, cookie)
+			edgeCookies = append(edgeCookies, cookie)
+		} else if domain == fbURL.Host {
+			fbCookies = append(fbCookies, cookie)
+		} else if domain == edgeURL.Host {
+			edgeCookies = append(edgeCookies, cookie)
+		}
+	}
+
+	if userID == "" {
+		return ParseError{"missing required c_user cookie in imported cookies"}
+	}
+
+	s.client.Jar.SetCookies(fbURL, fbCookies)
+	s.client.Jar.SetCookies(edgeURL, edgeCookies)
+	s.userID = userID
+
+	return nil
+}
+
+// parseNetscapeCookies parses cookies in the Netscape cookies.txt format,
+// where each line consists of the domain, whether the cookie applies to
+// subdomains, the path, whether the cookie is secure, the expiry as a Unix
+// timestamp, the name and the value, separated by tabs. The value may be
+// empty, in which case some exporters omit the trailing tab.
+func parseNetscapeCookies(r io.Reader) ([]importedCookie, error) {
+	var cookies []importedCookie
+
+	lineNum := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r\n")
+
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return nil, ParseError{"invalid cookies.txt line " +
+				strconv.Itoa(lineNum)}
+		}
+
+		expires, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, ParseError{"invalid cookie expiry on cookies.txt line " +
+				strconv.Itoa(lineNum)}
+		}
+
+		cookies = append(cookies, importedCookie{
+			Domain:         fields[0],
+			HostOnly:       strings.ToUpper(fields[1]) != "TRUE",
+			Path:           fields[2],
+			Secure:         strings.ToUpper(fields[3]) == "TRUE",
+			HTTPOnly:       httpOnly,
+			ExpirationDate: expires,
+			Name:           fields[5],
+			Value:          fields[6],
+		})
+	}
+
+	return cookies, scanner.Err()
+}
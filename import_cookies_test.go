@@ -0,0 +1,98 @@
+package messenger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNetscapeCookies(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []importedCookie
+		err   bool
+	}{
+		{
+			name: "cookie",
+			input: "# Netscape HTTP Cookie File\n" +
+				".facebook.com\tTRUE\t/\tTRUE\t1893456000\tc_user\t1234\n",
+			want: []importedCookie{{
+				Domain:         ".facebook.com",
+				Path:           "/",
+				Secure:         true,
+				ExpirationDate: 1893456000,
+				Name:           "c_user",
+				Value:          "1234",
+			}},
+		},
+		{
+			name:  "http only and host only",
+			input: "#HttpOnly_www.facebook.com\tFALSE\t/\tFALSE\t0\txs\ttoken\r\n",
+			want: []importedCookie{{
+				Domain:   "www.facebook.com",
+				HostOnly: true,
+				Path:     "/",
+				HTTPOnly: true,
+				Name:     "xs",
+				Value:    "token",
+			}},
+		},
+		{
+			name:  "empty value",
+			input: ".facebook.com\tTRUE\t/\tTRUE\t0\tpresence\t\n",
+			want: []importedCookie{{
+				Domain: ".facebook.com",
+				Path:   "/",
+				Secure: true,
+				Name:   "presence",
+			}},
+		},
+		{
+			name:  "empty value without trailing tab",
+			input: ".facebook.com\tTRUE\t/\tTRUE\t0\tpresence\n",
+			want: []importedCookie{{
+				Domain: ".facebook.com",
+				Path:   "/",
+				Secure: true,
+				Name:   "presence",
+			}},
+		},
+		{
+			name:  "blank lines and comments",
+			input: "\n# comment\n  \n",
+		},
+		{
+			name:  "too few fields",
+			input: "# comment\n.facebook.com\tTRUE\t/\tTRUE\tsecret\n",
+			err:   true,
+		},
+		{
+			name:  "invalid expiry",
+			input: ".facebook.com\tTRUE\t/\tTRUE\tsoon\tc_user\t1234\n",
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		cookies, err := parseNetscapeCookies(strings.NewReader(test.input))
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			} else if strings.Contains(err.Error(), "secret") ||
+				strings.Contains(err.Error(), "1234") {
+				t.Errorf("%s: error contains cookie: %v", test.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(cookies, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, cookies, test.want)
+		}
+	}
+}
@@ -2,14 +2,12 @@ package messenger
 
 import (
 	"errors"
-	"fmt"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,20 +21,38 @@ type listener struct {
 	form pullForm
 
 	lastMessage   time.Time
+	lastPull      time.Time
+	lastError     error
+	connState     ConnectionState
+	pending       atomic.Int64
 	activeRequest *http.Request
 	lastSync      time.Time
+	// syncFrom is the time that messages are synced from if none have been
+	// delivered yet, which is when Listen was called, or the restored last
+	// sync if there is one.
+	syncFrom      time.Time
+	lastTimestamp int64
+	catchUp       bool
+	resume        *ListenerState
+	stateMutex    *sync.Mutex
 	// TODO: Close functions are hackily thread safe.
 	shouldClose bool
+	listening   bool
 	closed      chan bool
 	closeMutex  *sync.Mutex
 
-	onMessage func(msg *Message)
-	onRead    func(thread Thread, userID string)
-	onTyping  func(thread Thread, userID string, typing bool)
-	onError   func(err error)
+	onMessage       func(msg *Message)
+	onEcho          func(msg *Message)
+	onRead          func(thread Thread, userID string)
+	onTyping        func(thread Thread, userID string, typing bool)
+	onFriendRequest func(userID string)
+	onError         func(err error)
 
-	processedThreadMessages map[string][]string
+	processedThreadMessages map[string]*processedThread
 	processedMutex          *sync.Mutex
+	processedClock          uint64
+	dedupSize               int
+	duplicatesDropped       int64
 }
 
 // ListenError is the type of error that will always be passed to OnError.
@@ -55,13 +71,31 @@ func (l ListenError) Error() string {
 // servers and blocks.
 func (s *Session) Listen() {
 	s.l.closeMutex = new(sync.Mutex)
+	s.l.closed = make(chan bool)
+	s.l.shouldClose = false
 
 	s.checkListeners()
 
 	s.l.lastMessage = time.Now()
-	s.l.lastSync = time.Now()
+	s.l.stateMutex.Lock()
+	s.l.listening = true
+	s.l.connState = StateConnecting
+	if s.l.lastSync.IsZero() {
+		s.l.lastSync = time.Now()
+	}
+	s.l.syncFrom = s.l.lastSync
+	catchUp := s.l.catchUp
+	s.l.catchUp = false
+	s.l.stateMutex.Unlock()
 
 	go func() {
+		if catchUp {
+			err := s.syncThreads()
+			if err != nil {
+				s.listenError("catch up thread sync", err)
+			}
+		}
+
 		for !s.l.shouldClose {
 			s.listenRequest()
 		}
@@ -70,12 +104,18 @@ func (s *Session) Listen() {
 	s.l.closeMutex.Lock()
 	<-s.l.closed
 	s.l.shouldClose = true
+	s.l.stateMutex.Lock()
+	s.l.listening = false
+	s.l.connState = StateClosed
+	s.l.stateMutex.Unlock()
 	s.l.closeMutex.Unlock()
 }
 
 func (s *Session) checkListeners() {
 	if s.l.onError == nil {
-		s.l.onError = func(err error) { fmt.Println(err) }
+		s.l.onError = func(err error) {
+			s.logger.Error("listen error", "error", err)
+		}
 	}
 
 	if s.l.onMessage == nil {
@@ -89,15 +129,44 @@ func (s *Session) checkListeners() {
 	if s.l.onTyping == nil {
 		s.l.onTyping = func(thread Thread, userID string, typing bool) {}
 	}
+
+	if s.l.onFriendRequest == nil {
+		s.l.onFriendRequest = func(userID string) {}
+	}
+}
+
+// listenError records the error and passes it to the OnError handler.
+func (s *Session) listenError(op string, err error) {
+	s.metrics.ListenError(op)
+
+	s.l.stateMutex.Lock()
+	s.l.lastError = ListenError{op, err}
+	s.l.stateMutex.Unlock()
+
+	if s.l.onError == nil {
+		s.logger.Error("listen error", "op", op, "error", err)
+		return
+	}
+
+	go s.l.onError(ListenError{op, err})
 }
 
 // OnMessage sets the handler for when a message is received.
 //
-// Receiving attachments isn't supported yet.
+// Only sticker attachments are received, other attachments are ignored.
 func (s *Session) OnMessage(handler func(msg *Message)) {
 	s.l.onMessage = handler
 }
 
+// OnEcho sets the handler for messages sent by the session's own account,
+// including messages sent from other clients such as the mobile app. Echoed
+// messages are not delivered unless a handler is set. The message's
+// OfflineThreadID can be used to match echoes of messages sent with
+// SendMessage.
+func (s *Session) OnEcho(handler func(msg *Message)) {
+	s.l.onEcho = handler
+}
+
 // OnRead sets the handler for when a message is read.
 func (s *Session) OnRead(handler func(thread Thread, userID string)) {
 	s.l.onRead = handler
@@ -113,12 +182,29 @@ func (s *Session) OnTyping(handler func(thread Thread, userID string, typing boo
 	s.l.onTyping = handler
 }
 
-// Close stops and returns all listeners on the session.
+// OnFriendRequest sets the handler for when a friend request is received.
+func (s *Session) OnFriendRequest(handler func(userID string)) {
+	s.l.onFriendRequest = handler
+}
+
+// Close stops and returns all listeners on the session, and saves the
+// session to the session store if one is set.
 func (s *Session) Close() error {
-	s.l.closed <- true
-	s.l.closeMutex.Lock()
-	s.l.closeMutex.Unlock()
-	return nil
+	s.stopListening()
+	return s.saveSession()
+}
+
+// stopListening stops the listener if the session is listening.
+func (s *Session) stopListening() {
+	s.l.stateMutex.Lock()
+	listening := s.l.listening
+	s.l.stateMutex.Unlock()
+
+	if listening {
+		s.l.closed <- true
+		s.l.closeMutex.Lock()
+		s.l.closeMutex.Unlock()
+	}
 }
 
 type pullMsgMeta struct {
@@ -127,14 +213,27 @@ type pullMsgMeta struct {
 		ThreadID    string `json:"threadFbId"`
 		OtherUserID string `json:"otherUserFbId"`
 	} `json:"threadKey"`
-	MessageID string `json:"messageId"`
-	Timestamp string `json:"timestamp"`
+	MessageID       string `json:"messageId"`
+	OfflineThreadID string `json:"offlineThreadingId"`
+	Timestamp       string `json:"timestamp"`
+	FolderID        struct {
+		SystemFolderID string `json:"systemFolderId"`
+	} `json:"folderId"`
 }
 
 type pullAction struct {
-	ThreadID  string `json:"thread_fbid"`
-	Author    string `json:"author"`
-	MessageID string `json:"message_id"`
+	ActionType      string `json:"action_type"`
+	ThreadID        string `json:"thread_fbid"`
+	OtherUserID     string `json:"other_user_fbid"`
+	Author          string `json:"author"`
+	MessageID       string `json:"message_id"`
+	OfflineThreadID string `json:"offline_threading_id"`
+	Body            string `json:"body"`
+	Timestamp       int64  `json:"timestamp"`
+	Folder          Folder `json:"folder"`
+
+	Attachments   []mercuryAttachment `json:"attachments"`
+	ProfileRanges []profileRange      `json:"profile_ranges"`
 }
 
 type pullMessage struct {
@@ -143,9 +242,11 @@ type pullMessage struct {
 	To     int64  `json:"to"`
 	Reader int64  `json:"reader"`
 	Delta  struct {
-		Class    string      `json:"class"`
-		Body     string      `json:"body"`
-		Metadata pullMsgMeta `json:"messageMetadata"`
+		Class       string            `json:"class"`
+		Body        string            `json:"body"`
+		Metadata    pullMsgMeta       `json:"messageMetadata"`
+		Attachments []deltaAttachment `json:"attachments"`
+		Data        deltaData         `json:"data"`
 	} `json:"delta"`
 	Event      string       `json:"event"`
 	Actions    []pullAction `json:"actions"`
@@ -170,7 +271,10 @@ type pullResponse struct {
 
 func (s *Session) listenRequest() {
 	idleSeconds := time.Now().Sub(s.l.lastMessage).Seconds()
+	s.l.stateMutex.Lock()
 	s.l.form.idleTime = int(idleSeconds)
+	form := s.l.form.form()
+	s.l.stateMutex.Unlock()
 
 	presence := s.generatePresence()
 	cookies := s.client.Jar.Cookies(fbURL)
@@ -181,32 +285,41 @@ func (s *Session) listenRequest() {
 	})
 	s.client.Jar.SetCookies(fbURL, cookies)
 
-	req, _ := http.NewRequest(http.MethodGet, chatURL+s.l.form.form().Encode(),
-		nil)
-	req.Header = defaultHeader()
+	req, _ := http.NewRequest(http.MethodGet, chatURL+form.Encode(), nil)
+	req.Header = s.defaultHeader()
 
+	start := time.Now()
 	resp, err := s.doRequest(req)
 	if err != nil {
-		go s.l.onError(ListenError{"HTTP listen", err})
+		s.metrics.PullCompleted(time.Since(start), err)
+		s.setConnState(StateReconnecting)
+		s.listenError("HTTP listen", err)
 		time.Sleep(time.Second)
 		return
 	}
 
 	defer resp.Body.Close()
 
-	respInfo, err := parseResponse(resp.Body)
+	respInfo, err := s.parseResponse(resp.Body)
+	s.metrics.PullCompleted(time.Since(start), err)
 	if err != nil {
-		go s.l.onError(ListenError{"parse listen", err})
+		s.setConnState(StateReconnecting)
+		s.listenError("parse listen", err)
 		time.Sleep(time.Second)
 		return
 	}
 
 	s.l.lastMessage = time.Now()
+	s.l.stateMutex.Lock()
+	s.l.lastPull = s.l.lastMessage
+	s.l.connState = StateConnected
 	s.l.form.messagesReceived += len(respInfo.Messages)
 	s.l.form.seq = respInfo.Seq
+	s.l.stateMutex.Unlock()
 
 	if respInfo.Type == "refresh" && respInfo.Reason == 110 {
-		go s.l.onError(ListenError{"listen response", ErrLoggedOut})
+		s.metrics.ServerError(ErrLoggedOut)
+		s.listenError("listen response", ErrLoggedOut)
 		if !s.l.shouldClose {
 			s.l.closed <- true
 			s.l.closeMutex.Lock()
@@ -217,13 +330,11 @@ func (s *Session) listenRequest() {
 	}
 
 	if respInfo.Type == "fullReload" {
-		if os.Getenv("MDEBUG") == "true" {
-			log.Println("debug start full reload")
-			s.fullReload()
-			log.Println("debug end full reload")
-		} else {
-			s.fullReload()
-		}
+		s.logger.Debug("start full reload")
+		s.setConnState(StateReconnecting)
+		s.fullReload()
+		s.setConnState(StateConnected)
+		s.logger.Debug("end full reload")
 
 		return
 	}
@@ -235,8 +346,11 @@ func (s *Session) listenRequest() {
 
 func (s *Session) processPull(resp pullResponse) {
 	if resp.Type == "lb" {
+		s.metrics.Reconnected()
+		s.l.stateMutex.Lock()
 		s.l.form.stickyToken = resp.Sticky.Token
 		s.l.form.stickyPool = resp.Sticky.Pool
+		s.l.stateMutex.Unlock()
 	}
 
 	for _, msg := range resp.Messages {
@@ -245,7 +359,27 @@ func (s *Session) processPull(resp pullResponse) {
 				continue
 			}
 
-			s.handleDeltaMessage(msg.Delta.Body, msg.Delta.Metadata)
+			var attachments []mercuryAttachment
+			for _, attachment := range msg.Delta.Attachments {
+				mercury, err := attachment.mercury()
+				if err != nil {
+					s.logger.Warn("failed to parse attachment", "error", err)
+					continue
+				}
+
+				attachments = append(attachments, mercury)
+			}
+
+			mentions, err := msg.Delta.Data.mentions()
+			if err != nil {
+				s.logger.Warn("failed to parse mentions", "error", err)
+			}
+
+			handler := s.handleDeltaMessage(msg.Delta.Body,
+				msg.Delta.Metadata, convertAttachments(attachments), mentions)
+			if handler != nil {
+				s.dispatch(handler)
+			}
 		} else if msg.Type == "messaging" {
 			if msg.Event == "read_receipt" {
 				from := strconv.FormatInt(msg.Reader, 10)
@@ -258,7 +392,7 @@ func (s *Session) processPull(resp pullResponse) {
 					thread.IsGroup = true
 				}
 
-				go s.l.onRead(thread, from)
+				s.dispatch(func() { s.l.onRead(thread, from) })
 			}
 		} else if msg.Type == "typ" {
 			from := strconv.FormatInt(msg.From, 10)
@@ -271,22 +405,34 @@ func (s *Session) processPull(resp pullResponse) {
 				thread.IsGroup = true
 			}
 
-			go s.l.onTyping(thread, from, msg.St > 0)
+			s.dispatch(func() { s.l.onTyping(thread, from, msg.St > 0) })
+		} else if msg.Type == "jewel_requests_add" {
+			from := strconv.FormatInt(msg.From, 10)
+			s.dispatch(func() { s.l.onFriendRequest(from) })
 		}
 	}
 }
 
-func (s *Session) handleDeltaMessage(body string, meta pullMsgMeta) {
-	if meta.Sender == s.userID {
-		return
+// handleDeltaMessage processes a received message, returning the call to
+// its handler to be dispatched, or nil if it shouldn't be delivered.
+func (s *Session) handleDeltaMessage(body string, meta pullMsgMeta,
+	attachments []Attachment, mentions []Mention) func() {
+	isEcho := meta.Sender == s.userID
+	if isEcho && s.l.onEcho == nil {
+		return nil
 	}
 
 	threadID := meta.ThreadKey.ThreadID
 	isGroup := true
 	if threadID == "" {
-		threadID = meta.Sender
+		threadID = meta.ThreadKey.OtherUserID
 		isGroup = false
 	}
+	if threadID == "" {
+		threadID = meta.Sender
+	}
+
+	folder := Folder(strings.ToLower(meta.FolderID.SystemFolderID))
 
 	msg := &Message{
 		FromUserID: meta.Sender,
@@ -294,50 +440,61 @@ func (s *Session) handleDeltaMessage(body string, meta pullMsgMeta) {
 			ThreadID: threadID,
 			IsGroup:  isGroup,
 		},
-		Body:      body,
-		MessageID: meta.MessageID,
+		Body:             body,
+		Attachments:      attachments,
+		Mentions:         mentions,
+		MessageID:        meta.MessageID,
+		IsMessageRequest: folder.isRequest(),
+		offlineThreadID:  meta.OfflineThreadID,
+	}
+
+	if s.markProcessed(threadID, msg.MessageID) {
+		return nil
+	}
+
+	timestamp, err := strconv.ParseInt(meta.Timestamp, 10, 64)
+	if err == nil {
+		s.updateLastTimestamp(timestamp)
+	}
+
+	if isEcho {
+		return func() { s.l.onEcho(msg) }
 	}
 
-	go s.l.onMessage(msg)
+	s.metrics.MessageReceived(threadType(msg.Thread))
+	return func() { s.l.onMessage(msg) }
 }
 
 func (s *Session) fullReload() {
+	s.metrics.Reconnected()
+
 	func() {
+		s.l.stateMutex.Lock()
+		lastSync := s.l.lastSync
+		s.l.stateMutex.Unlock()
+
 		form := make(url.Values)
-		form.Set("lastSync", strconv.FormatInt(s.l.lastSync.Unix(), 10))
+		form.Set("lastSync", strconv.FormatInt(lastSync.Unix(), 10))
 		form = s.addFormMeta(form)
 
 		req, _ := http.NewRequest(http.MethodGet, syncURL+form.Encode(), nil)
-		req.Header = defaultHeader()
+		req.Header = s.defaultHeader()
 
 		resp, err := s.doRequest(req)
 		if err != nil {
-			s.l.onError(ListenError{"reload sync", err})
+			s.listenError("reload sync", err)
 			return
 		}
 
+		s.l.stateMutex.Lock()
 		s.l.lastSync = time.Now()
+		s.l.stateMutex.Unlock()
 
 		resp.Body.Close()
 	}()
 
-	func() {
-		form := make(url.Values)
-		form.Set("client", "mercury")
-		form.Set("folders[0]", "inbox")
-		form.Set("last_action_timestamp",
-			strconv.FormatInt((time.Now().UnixNano()/1e6)-60, 10))
-		form = s.addFormMeta(form)
-
-		req, _ := http.NewRequest(http.MethodPost, threadSyncURL,
-			strings.NewReader(form.Encode()))
-
-		resp, err := s.doRequest(req)
-		if err != nil {
-			s.l.onError(ListenError{"reload thread sync", err})
-			return
-		}
-
-		resp.Body.Close()
-	}()
+	err := s.syncThreads()
+	if err != nil {
+		s.listenError("reload thread sync", err)
+	}
 }
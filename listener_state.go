@@ -0,0 +1,67 @@
+package messenger
+
+import "time"
+
+// ListenerState represents the listener's position in the chat server's
+// message stream. It can be saved with the session and passed to
+// ResumeListener so that a restarted session continues listening where it
+// left off, rather than from the start of a new stream. The sticky pool and
+// token aren't included, as the chat server assigns new ones on every
+// connect.
+type ListenerState struct {
+	Seq              int `json:"seq"`
+	MessagesReceived int `json:"messages_received"`
+
+	// LastTimestamp is the timestamp of the last message delivered by the
+	// listener, in milliseconds since the Unix epoch.
+	LastTimestamp int64     `json:"last_timestamp"`
+	LastSync      time.Time `json:"last_sync"`
+}
+
+// ListenerState returns the current state of the listener.
+func (s *Session) ListenerState() ListenerState {
+	s.l.stateMutex.Lock()
+	defer s.l.stateMutex.Unlock()
+
+	return ListenerState{
+		Seq:              s.l.form.seq,
+		MessagesReceived: s.l.form.messagesReceived,
+		LastTimestamp:    s.l.lastTimestamp,
+		LastSync:         s.l.lastSync,
+	}
+}
+
+// ResumeListener sets the state the listener should resume from when
+// ConnectToChat is next called. Messages received after the state's
+// LastTimestamp are fetched and delivered when Listen is called.
+func (s *Session) ResumeListener(state ListenerState) {
+	s.l.stateMutex.Lock()
+	defer s.l.stateMutex.Unlock()
+
+	s.l.resume = &state
+}
+
+// applyResume applies the state set by ResumeListener to the pull form,
+// and must be called with the state mutex held.
+func (s *Session) applyResume() {
+	if s.l.resume == nil {
+		return
+	}
+
+	s.l.form.seq = s.l.resume.Seq
+	s.l.form.messagesReceived = s.l.resume.MessagesReceived
+	s.l.lastTimestamp = s.l.resume.LastTimestamp
+	s.l.lastSync = s.l.resume.LastSync
+	s.l.catchUp = s.l.lastTimestamp > 0
+	s.l.resume = nil
+}
+
+// updateLastTimestamp records the timestamp of a delivered message if it's
+// newer than the last recorded timestamp.
+func (s *Session) updateLastTimestamp(timestamp int64) {
+	s.l.stateMutex.Lock()
+	if timestamp > s.l.lastTimestamp {
+		s.l.lastTimestamp = timestamp
+	}
+	s.l.stateMutex.Unlock()
+}
@@ -0,0 +1,109 @@
+package messenger
+
+import (
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+)
+
+// Logger is the interface used by the session to log debugging information
+// and errors. Arguments after the message are alternating keys and values.
+// *slog.Logger satisfies Logger.
+//
+// Cookies, fb_dtsg tokens and message bodies are redacted from all output.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+// defaultLogger returns the logger used by sessions unless one is set with
+// SetLogger, which logs warnings and errors to stderr. Debug logging is
+// enabled by setting the MDEBUG environment variable to "true".
+func defaultLogger() Logger {
+	level := slog.LevelWarn
+	if os.Getenv("MDEBUG") == "true" {
+		level = slog.LevelDebug
+	}
+
+	return slog.New(slog.NewTextHandler(os.Stderr,
+		&slog.HandlerOptions{Level: level}))
+}
+
+// SetLogger sets the logger used by the session. A nil logger disables
+// logging.
+func (s *Session) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+
+	s.logger = logger
+}
+
+// redactedParams are the query parameters and form fields whose values are
+// redacted from logged URLs.
+var redactedParams = []string{"fb_dtsg", "ttstamp", "h", "sticky_token"}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, param := range redactedParams {
+		if query.Get(param) != "" {
+			query.Set(param, "REDACTED")
+		}
+	}
+
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// redactURLString redacts the URL in its string form, such as the URL of a
+// *url.Error, which includes the query string of the failed request.
+func redactURLString(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "REDACTED"
+	}
+
+	return redactURL(u)
+}
+
+var redactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`("(?:body|snippet|fb_dtsg|token|dtsg)"\s*:\s*")(?:[^"\\]|\\.)*(")`),
+	regexp.MustCompile(`(name=\\?"(?:fb_dtsg|h)\\?" value=\\?")[^"\\]*(\\?")`),
+}
+
+// redactResponse removes tokens and message bodies from a response so that
+// it can be logged.
+func redactResponse(data []byte) string {
+	for _, pattern := range redactPatterns {
+		data = pattern.ReplaceAll(data, []byte("${1}REDACTED${2}"))
+	}
+
+	return string(data)
+}
+
+// responseBody wraps a response body to associate it with the ID of the
+// request that it's in response to, for logging.
+type responseBody struct {
+	io.ReadCloser
+	requestID uint64
+}
+
+func requestID(rd io.Reader) uint64 {
+	if body, ok := rd.(*responseBody); ok {
+		return body.requestID
+	}
+
+	return 0
+}
@@ -0,0 +1,125 @@
+package messenger
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.facebook.com/", "https://www.facebook.com/"},
+		{
+			"https://0-edge-chat.facebook.com/pull?seq=1&sticky_token=secret",
+			"https://0-edge-chat.facebook.com/pull?seq=1&sticky_token=REDACTED",
+		},
+		{
+			"https://www.facebook.com/a?fb_dtsg=secret&h=secret&ttstamp=secret",
+			"https://www.facebook.com/a?fb_dtsg=REDACTED&h=REDACTED&ttstamp=REDACTED",
+		},
+		{
+			"https://www.facebook.com/a?fb_dtsg=&uid=1000",
+			"https://www.facebook.com/a?fb_dtsg=&uid=1000",
+		},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := redactURL(u); got != test.want {
+			t.Errorf("redactURL(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
+
+func TestRedactResponse(t *testing.T) {
+	tests := []struct {
+		data   string
+		want   string
+		secret string
+	}{
+		{`{"seq":1}`, `{"seq":1}`, ""},
+		{
+			`{"body":"hello \"there\"","snippet":"hi","seq":1}`,
+			`{"body":"REDACTED","snippet":"REDACTED","seq":1}`,
+			"hello",
+		},
+		{
+			`{"fb_dtsg": "secret","token":"secret","dtsg":"secret"}`,
+			`{"fb_dtsg": "REDACTED","token":"REDACTED","dtsg":"REDACTED"}`,
+			"secret",
+		},
+		{
+			`<input type="hidden" name="fb_dtsg" value="secret" />`,
+			`<input type="hidden" name="fb_dtsg" value="REDACTED" />`,
+			"secret",
+		},
+		{
+			`{"markup":"<input name=\"h\" value=\"secret\">"}`,
+			`{"markup":"<input name=\"h\" value=\"REDACTED\">"}`,
+			"secret",
+		},
+	}
+
+	for _, test := range tests {
+		got := redactResponse([]byte(test.data))
+		if got != test.want {
+			t.Errorf("redactResponse(%q) = %q, want %q", test.data, got,
+				test.want)
+		}
+
+		if test.secret != "" && strings.Contains(got, test.secret) {
+			t.Errorf("redactResponse(%q) leaks %q", test.data, test.secret)
+		}
+	}
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("boom")
+}
+
+type recordingLogger struct {
+	nopLogger
+	output []string
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) {
+	l.output = append(l.output, fmt.Sprint(msg, args))
+}
+
+func TestDoRequestRedactsErrors(t *testing.T) {
+	s := NewSession()
+	logger := new(recordingLogger)
+	s.SetLogger(logger)
+	s.SetTransport(failingTransport{})
+
+	req, _ := http.NewRequest(http.MethodGet, syncURL+
+		"lastSync=1&fb_dtsg=SECRETDTSG&ttstamp=SECRETTTSTAMP", nil)
+	_, err := s.doRequest(req)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	output := append(logger.output, err.Error())
+	for _, line := range output {
+		if strings.Contains(line, "SECRET") {
+			t.Errorf("output contains secret: %s", line)
+		}
+	}
+
+	if !strings.Contains(err.Error(), "boom") ||
+		!strings.Contains(err.Error(), "lastSync=1") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
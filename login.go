@@ -2,6 +2,7 @@ package messenger
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -14,7 +15,8 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
-// Errors that are returned by Login.
+// Errors that are returned by Login. Checkpoints are returned as a
+// CheckpointError, which matches ErrLoginCheckpoint with errors.Is.
 var (
 	ErrLoginError      = errors.New("messenger: incorrect login credentials")
 	ErrLoginCheckpoint = errors.New("messenger: login checkpoint")
@@ -24,7 +26,7 @@ var jsCookiePattern = regexp.MustCompile("\\[\"(_js_[^\"]+)\",\"([^\"]+)\",")
 
 func (s *Session) createLoginRequest(email, password string) (*http.Request, error) {
 	req, _ := http.NewRequest(http.MethodGet, facebookURL, nil)
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	resp, err := s.doRequest(req)
 	if err != nil {
@@ -73,13 +75,13 @@ func (s *Session) createLoginRequest(email, password string) (*http.Request, err
 	form.Set("pass", password)
 	form.Set("default_persistent", "1")
 	form.Set("lgnjs", strconv.FormatInt(time.Now().Unix(), 10))
-	_, offset := time.Now().Zone()
-	form.Set("timezone", strconv.Itoa(-offset/60))
-	form.Set("lgndim", "eyJ3IjoxNDQwLCJoIjo5MDAsImF3IjoxNDQwLCJhaCI6OTAwLCJjIjoyNH0=")
+	form.Set("timezone", strconv.Itoa(s.profile.timezoneOffset()))
+	form.Set("lgndim", s.profile.loginDimensions())
+	form.Set("locale", s.profile.Locale)
 	form.Set("next", "https://www.facebook.com/")
 
 	loginReq, _ := http.NewRequest(http.MethodPost, loginURL, strings.NewReader(form.Encode()))
-	loginReq.Header = defaultHeader()
+	loginReq.Header = s.defaultHeader()
 	loginReq.Header.Set("Content-Type", formURLEncoded)
 
 	return loginReq, nil
@@ -87,6 +89,8 @@ func (s *Session) createLoginRequest(email, password string) (*http.Request, err
 
 // Login logs the session in to a Facebook account.
 func (s *Session) Login(email, password string) error {
+	s.setLoggingOut(false)
+
 	req, err := s.createLoginRequest(email, password)
 	if err != nil {
 		return err
@@ -104,7 +108,15 @@ func (s *Session) Login(email, password string) error {
 	}
 
 	err = handleLoginRedirect(resp)
-	if err != nil {
+	if cpErr, ok := err.(CheckpointError); ok {
+		s.checkpointURL = cpErr.URL
+		data, err := s.getCheckpoint(context.Background(), cpErr.URL)
+		if err == nil {
+			cpErr.Type = classifyCheckpoint(data)
+		}
+
+		return cpErr
+	} else if err != nil {
 		return err
 	}
 
@@ -117,17 +129,21 @@ func handleLoginRedirect(resp *http.Response) error {
 		return err
 	}
 
-	if strings.Contains(redirURL.String(), "https://www.facebook.com/checkpoint") {
-		return ErrLoginCheckpoint
+	return handleLoginRedirectURL(redirURL.String())
+}
+
+func handleLoginRedirectURL(redirURL string) error {
+	if strings.Contains(redirURL, "https://www.facebook.com/checkpoint") {
+		return CheckpointError{Type: CheckpointUnknown, URL: redirURL}
 	}
 
-	if strings.Contains(redirURL.String(), "https://www.facebook.com/login.php?") {
+	if strings.Contains(redirURL, "https://www.facebook.com/login.php?") {
 		return ErrLoginError
 	}
 
-	if redirURL.String() == "https://www.facebook.com/" || redirURL.String() == "https://www.facebook.com" {
+	if redirURL == "https://www.facebook.com/" || redirURL == "https://www.facebook.com" {
 		return nil
 	}
 
-	return ParseError{"unexpected redirect to " + redirURL.String()}
+	return ParseError{"unexpected redirect to " + redirURL}
 }
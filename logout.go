@@ -0,0 +1,152 @@
+package messenger
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Logout logs the session out of Facebook, invalidating it on the server. On
+// success, any active listener is stopped, the session's cookies are cleared
+// and the session is removed from the session store. If the server side
+// logout fails, the session is left untouched so that it may be retried.
+//
+// The session isn't saved to the session store while logging out, or after
+// the logout succeeds until the session is logged in or restored again.
+func (s *Session) Logout(ctx context.Context) error {
+	s.setLoggingOut(true)
+
+	err := s.logout(ctx)
+	if err != nil {
+		s.setLoggingOut(false)
+		return err
+	}
+
+	s.stopListening()
+	s.clearSession()
+
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	s.jar.takeChanged()
+	if s.store != nil {
+		return s.store.Save(nil)
+	}
+
+	return nil
+}
+
+// logout performs the server side logout. The logout is successful if the
+// server redirects to somewhere other than a checkpoint or responds
+// successfully, and clears the c_user cookie.
+func (s *Session) logout(ctx context.Context) error {
+	if s.meta.dtsg == "" {
+		err := s.populateMeta()
+		if err != nil {
+			return err
+		}
+	}
+
+	h, err := s.logoutToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	form := make(url.Values)
+	form.Set("fb_dtsg", s.meta.dtsg)
+	form.Set("ref", "mb")
+	form.Set("h", h)
+
+	req, _ := http.NewRequest(http.MethodPost, logoutURL,
+		strings.NewReader(form.Encode()))
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		urlErr, ok := err.(*url.Error)
+		if !ok || urlErr.Err != errNoRedirects {
+			return err
+		}
+
+		if strings.Contains(urlErr.URL, "/checkpoint") {
+			return CheckpointError{Type: CheckpointUnknown, URL: urlErr.URL}
+		}
+	} else {
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return ParseError{"logout failed with status: " + resp.Status}
+		}
+	}
+
+	for _, cookie := range s.client.Jar.Cookies(fbURL) {
+		if cookie.Name == "c_user" {
+			return ParseError{"logout was not confirmed by the server"}
+		}
+	}
+
+	return nil
+}
+
+// logoutToken retrieves the "h" token required to log out from the settings
+// menu.
+func (s *Session) logoutToken(ctx context.Context) (string, error) {
+	form := make(url.Values)
+	form.Set("pmid", "0")
+	form = s.addFormMeta(form)
+
+	req, _ := http.NewRequest(http.MethodPost, logoutMenuURL,
+		strings.NewReader(form.Encode()))
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return searchBetween(data, "name=\\\"h\\\" value=\\\"", '\\')
+}
+
+// clearSession clears the session's cookies, tokens and listener state.
+func (s *Session) clearSession() {
+	s.requestMutex.Lock()
+	defer s.requestMutex.Unlock()
+
+	cookies, _ := cookiejar.New(nil)
+
+	s.jar.mutex.Lock()
+	s.jar.CookieJar = cookies
+	s.jar.expires = make(map[string]time.Time)
+	s.jar.mutex.Unlock()
+
+	s.userID = ""
+	s.clientID = ""
+	s.checkpointURL = ""
+	s.meta = meta{
+		req: 1,
+	}
+
+	s.l.stateMutex.Lock()
+	s.l.form = pullForm{}
+	s.l.lastTimestamp = 0
+	s.l.lastSync = time.Time{}
+	s.l.syncFrom = time.Time{}
+	s.l.resume = nil
+	s.l.catchUp = false
+	s.l.stateMutex.Unlock()
+}
@@ -0,0 +1,98 @@
+package messenger
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// logoutTransport serves the logout menu, and responds to the logout with
+// the given status, location and cookies.
+type logoutTransport struct {
+	status   int
+	location string
+	cookies  []string
+}
+
+func (l logoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body: ioutil.NopCloser(strings.NewReader(
+			`for (;;); {"markup":"<input name=\"h\" value=\"token\">"}`)),
+		Request: req,
+	}
+
+	if req.URL.Path == "/logout.php" {
+		resp.StatusCode = l.status
+		resp.Body = ioutil.NopCloser(strings.NewReader(""))
+		if l.location != "" {
+			resp.Header.Set("Location", l.location)
+		}
+		for _, cookie := range l.cookies {
+			resp.Header.Add("Set-Cookie", cookie)
+		}
+	}
+
+	return resp, nil
+}
+
+func TestLogout(t *testing.T) {
+	clearUser := "c_user=deleted; expires=Thu, 01 Jan 1970 00:00:01 GMT; " +
+		"Max-Age=0; path=/; domain=.facebook.com"
+
+	tests := []struct {
+		name      string
+		transport logoutTransport
+		success   bool
+	}{
+		{"redirect", logoutTransport{http.StatusFound,
+			"https://www.facebook.com/", []string{clearUser}}, true},
+		{"success", logoutTransport{http.StatusOK, "",
+			[]string{clearUser}}, true},
+		{"checkpoint", logoutTransport{http.StatusFound,
+			"https://www.facebook.com/checkpoint/?next", []string{clearUser}},
+			false},
+		{"redirect without logout", logoutTransport{http.StatusFound,
+			"https://www.facebook.com/", nil}, false},
+		{"error page", logoutTransport{http.StatusOK, "", nil}, false},
+		{"server error", logoutTransport{http.StatusInternalServerError, "",
+			[]string{clearUser}}, false},
+	}
+
+	for _, test := range tests {
+		s := NewSession()
+		s.SetLogger(nil)
+		s.SetTransport(test.transport)
+		s.client.Jar.SetCookies(fbURL, []*http.Cookie{
+			{Name: "c_user", Value: "1000", Domain: ".facebook.com"},
+		})
+		s.userID = "1000"
+		s.meta.dtsg = "dtsg"
+
+		store := NewMemoryStore()
+		store.Save([]byte("session"))
+		s.SetSessionStore(store)
+
+		err := s.Logout(context.Background())
+		_, loadErr := store.Load()
+
+		if test.success {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			if loadErr != ErrNoSession || s.userID != "" {
+				t.Errorf("%s: session wasn't cleared", test.name)
+			}
+		} else {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			if loadErr != nil || s.userID != "1000" {
+				t.Errorf("%s: session was cleared", test.name)
+			}
+		}
+	}
+}
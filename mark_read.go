@@ -14,7 +14,7 @@ func (s *Session) MarkAsRead(thread Thread) error {
 
 	req, _ := http.NewRequest(http.MethodPost, readStatusURL,
 		strings.NewReader(form.Encode()))
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	resp, err := s.doRequest(req)
 	if err != nil {
@@ -23,6 +23,6 @@ func (s *Session) MarkAsRead(thread Thread) error {
 
 	defer resp.Body.Close()
 
-	_, err = parseResponse(resp.Body)
+	_, err = s.parseResponse(resp.Body)
 	return err
 }
@@ -0,0 +1,141 @@
+package messenger
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Mention represents a mention of a user in the body of a message. Offset and
+// Length are in UTF-16 code units, as used by Messenger.
+type Mention struct {
+	UserID string
+	Offset int
+	Length int
+}
+
+// deltaMention represents a mention of a delta message, which are encoded
+// as a JSON string in the prng field of the delta's data.
+type deltaMention struct {
+	UserID flexibleID `json:"i"`
+	Offset int        `json:"o"`
+	Length int        `json:"l"`
+}
+
+// deltaData represents the data of a delta message.
+type deltaData struct {
+	Prng string `json:"prng"`
+}
+
+func (d deltaData) mentions() ([]Mention, error) {
+	if d.Prng == "" {
+		return nil, nil
+	}
+
+	var deltaMentions []deltaMention
+	err := json.Unmarshal([]byte(d.Prng), &deltaMentions)
+	if err != nil {
+		return nil, err
+	}
+
+	var mentions []Mention
+	for _, mention := range deltaMentions {
+		mentions = append(mentions, Mention{
+			UserID: string(mention.UserID),
+			Offset: mention.Offset,
+			Length: mention.Length,
+		})
+	}
+
+	return mentions, nil
+}
+
+// profileRange represents a mention of a message returned by the thread
+// sync.
+type profileRange struct {
+	UserID flexibleID `json:"id"`
+	Offset int        `json:"offset"`
+	Length int        `json:"length"`
+}
+
+func convertProfileRanges(ranges []profileRange) []Mention {
+	var mentions []Mention
+	for _, r := range ranges {
+		mentions = append(mentions, Mention{
+			UserID: string(r.UserID),
+			Offset: r.Offset,
+			Length: r.Length,
+		})
+	}
+
+	return mentions
+}
+
+func addMentions(form url.Values, mentions []Mention) {
+	for i, mention := range mentions {
+		prefix := "profile_xmd[" + strconv.Itoa(i) + "]"
+		form.Set(prefix+"[id]", mention.UserID)
+		form.Set(prefix+"[offset]", strconv.Itoa(mention.Offset))
+		form.Set(prefix+"[length]", strconv.Itoa(mention.Length))
+		form.Set(prefix+"[type]", "p")
+	}
+}
+
+// FormatMentions replaces every "@{id}" placeholder in template with "@"
+// followed by the user's name in names, and returns the resulting body with
+// the mentions to set on the Message. Placeholders of IDs missing from names
+// are left as is.
+//
+//	body, mentions := messenger.FormatMentions("Hey @{1234}",
+//		map[string]string{"1234": "Jason"})
+func FormatMentions(template string, names map[string]string) (string,
+	[]Mention) {
+	var body strings.Builder
+	var mentions []Mention
+	offset := 0
+
+	for {
+		start := strings.Index(template, "@{")
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		id := template[start+2 : end]
+		name, ok := names[id]
+		if !ok {
+			body.WriteString(template[:end+1])
+			offset += utf16Len(template[:end+1])
+			template = template[end+1:]
+			continue
+		}
+
+		body.WriteString(template[:start])
+		offset += utf16Len(template[:start])
+
+		text := "@" + name
+		body.WriteString(text)
+		mentions = append(mentions, Mention{
+			UserID: id,
+			Offset: offset,
+			Length: utf16Len(text),
+		})
+		offset += utf16Len(text)
+
+		template = template[end+1:]
+	}
+
+	body.WriteString(template)
+	return body.String(), mentions
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
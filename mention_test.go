@@ -0,0 +1,110 @@
+package messenger
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFormatMentions(t *testing.T) {
+	names := map[string]string{
+		"1001": "Alice",
+		"1002": "Jasön",
+		"1003": "😀 Bob",
+	}
+
+	tests := []struct {
+		template string
+		body     string
+		mentions []Mention
+	}{
+		{"", "", nil},
+		{"no mentions", "no mentions", nil},
+		{"Hey @{1001}", "Hey @Alice", []Mention{{"1001", 4, 6}}},
+		{"@{1001} and @{1002}!", "@Alice and @Jasön!", []Mention{
+			{"1001", 0, 6},
+			{"1002", 11, 6},
+		}},
+		// Offsets and lengths are in UTF-16 code units, where the emoji
+		// takes two.
+		{"😀 @{1003} @{1001}", "😀 @😀 Bob @Alice", []Mention{
+			{"1003", 3, 7},
+			{"1001", 11, 6},
+		}},
+		{"@{9999} @{1001}", "@{9999} @Alice", []Mention{{"1001", 8, 6}}},
+		{"@{1001", "@{1001", nil},
+		{"@{1001}@{1001}", "@Alice@Alice", []Mention{
+			{"1001", 0, 6},
+			{"1001", 6, 6},
+		}},
+		{"email@{1001}}", "email@Alice}", []Mention{{"1001", 5, 6}}},
+	}
+
+	for _, test := range tests {
+		body, mentions := FormatMentions(test.template, names)
+		if body != test.body {
+			t.Errorf("FormatMentions(%q) body = %q, want %q", test.template,
+				body, test.body)
+		}
+
+		if !reflect.DeepEqual(mentions, test.mentions) {
+			t.Errorf("FormatMentions(%q) mentions = %v, want %v",
+				test.template, mentions, test.mentions)
+		}
+	}
+}
+
+func TestDeltaDataMentions(t *testing.T) {
+	tests := []struct {
+		prng     string
+		mentions []Mention
+		err      bool
+	}{
+		{"", nil, false},
+		{"[]", nil, false},
+		{`[{"i":"1001","o":4,"l":6}]`, []Mention{{"1001", 4, 6}}, false},
+		{`[{"i":1001,"o":0,"l":6},{"i":"1002","o":7,"l":4}]`, []Mention{
+			{"1001", 0, 6},
+			{"1002", 7, 4},
+		}, false},
+		{`[{"i":`, nil, true},
+	}
+
+	for _, test := range tests {
+		mentions, err := deltaData{Prng: test.prng}.mentions()
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected error", test.prng)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.prng, err)
+		}
+
+		if !reflect.DeepEqual(mentions, test.mentions) {
+			t.Errorf("%q: got %v, want %v", test.prng, mentions, test.mentions)
+		}
+	}
+}
+
+func TestAddMentions(t *testing.T) {
+	form := make(url.Values)
+	addMentions(form, []Mention{{"1001", 0, 6}, {"1002", 11, 6}})
+
+	want := url.Values{
+		"profile_xmd[0][id]":     []string{"1001"},
+		"profile_xmd[0][offset]": []string{"0"},
+		"profile_xmd[0][length]": []string{"6"},
+		"profile_xmd[0][type]":   []string{"p"},
+		"profile_xmd[1][id]":     []string{"1002"},
+		"profile_xmd[1][offset]": []string{"11"},
+		"profile_xmd[1][length]": []string{"6"},
+		"profile_xmd[1][type]":   []string{"p"},
+	}
+
+	if !reflect.DeepEqual(form, want) {
+		t.Errorf("got %v, want %v", form, want)
+	}
+}
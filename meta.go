@@ -9,6 +9,8 @@ import (
 	"strings"
 )
 
+var securityCheckHeader = []byte(`<h2 id="security_check_header">Security check</h2>`)
+
 type meta struct {
 	req      int64
 	revision string
@@ -18,7 +20,7 @@ type meta struct {
 
 func (s *Session) populateMeta() error {
 	req, _ := http.NewRequest(http.MethodGet, facebookURL, nil)
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	resp, err := s.doRequest(req)
 	if err != nil {
@@ -32,9 +34,8 @@ func (s *Session) populateMeta() error {
 		return err
 	}
 
-	if bytes.Contains(data,
-		[]byte(`<h2 id="security_check_header">Security check</h2>`)) {
-		return ErrLoginCheckpoint
+	if bytes.Contains(data, securityCheckHeader) {
+		return CheckpointError{Type: classifyCheckpoint(data), URL: facebookURL}
 	}
 
 	s.meta.dtsg, err = searchBetween(data, "name=\"fb_dtsg\" value=\"", '"')
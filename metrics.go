@@ -0,0 +1,77 @@
+package messenger
+
+import "time"
+
+// Thread types used as labels by Metrics.
+const (
+	ThreadTypeUser  = "user"
+	ThreadTypeGroup = "group"
+)
+
+// Metrics is the interface used by the session to record metrics. See the
+// prommetrics package for a Prometheus implementation, and the oteltrace
+// package for tracing the session's requests.
+type Metrics interface {
+	// PullCompleted is called after each long poll to the chat server with
+	// its latency, and the error if the poll failed.
+	PullCompleted(latency time.Duration, err error)
+	// Reconnected is called when the chat server requires the listener to
+	// reconnect or reload.
+	Reconnected()
+	// ListenError is called with the operation of each ListenError.
+	ListenError(op string)
+	// MessageReceived is called for each message delivered to OnMessage.
+	MessageReceived(threadType string)
+	// MessageSent is called after each SendMessage with its latency, and the
+	// error if sending failed.
+	MessageSent(threadType string, latency time.Duration, err error)
+	// ServerError is called whenever the server responds with ErrLoggedOut
+	// or ErrUnknown.
+	ServerError(err error)
+	// DuplicateDropped is called when a duplicate message is suppressed.
+	DuplicateDropped()
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) PullCompleted(latency time.Duration, err error)                  {}
+func (nopMetrics) Reconnected()                                                    {}
+func (nopMetrics) ListenError(op string)                                           {}
+func (nopMetrics) MessageReceived(threadType string)                               {}
+func (nopMetrics) MessageSent(threadType string, latency time.Duration, err error) {}
+func (nopMetrics) ServerError(err error)                                           {}
+func (nopMetrics) DuplicateDropped()                                               {}
+
+// SetMetrics sets the metrics recorder of the session. A nil recorder
+// disables metrics.
+func (s *Session) SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+
+	s.metrics = metrics
+}
+
+func threadType(thread Thread) string {
+	if thread.IsGroup {
+		return ThreadTypeGroup
+	}
+
+	return ThreadTypeUser
+}
+
+// responseError returns the error for the error code of a response from
+// Facebook, and records it.
+func (s *Session) responseError(code int) error {
+	var err error
+	if code == loggedOutError {
+		err = ErrLoggedOut
+	} else if code > 0 {
+		err = ErrUnknown
+	} else {
+		return nil
+	}
+
+	s.metrics.ServerError(err)
+	return err
+}
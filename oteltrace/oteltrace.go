@@ -0,0 +1,63 @@
+// Package oteltrace provides an http.RoundTripper that traces the requests
+// made by a messenger.Session with OpenTelemetry.
+//
+//	session.SetTransport(oteltrace.NewTransport(nil,
+//		otel.Tracer("github.com/1lann/messenger")))
+package oteltrace
+
+import (
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport is an http.RoundTripper that creates a span for every request.
+// Query strings aren't recorded as they contain session tokens.
+type Transport struct {
+	next   http.RoundTripper
+	tracer trace.Tracer
+}
+
+// NewTransport returns a Transport that performs requests with next and
+// records spans with tracer. If next is nil, http.DefaultTransport is used.
+func NewTransport(next http.RoundTripper, tracer trace.Tracer) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{
+		next:   next,
+		tracer: tracer,
+	}
+}
+
+// RoundTrip performs the request within a span.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(),
+		"messenger "+req.Method+" "+req.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		))
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code",
+		resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+
+	return resp, nil
+}
@@ -3,41 +3,37 @@ package messenger
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
-	"os"
 )
 
-func parseResponse(rd io.Reader) (pullResponse, error) {
+func (s *Session) parseResponse(rd io.Reader) (pullResponse, error) {
 	var result pullResponse
-	err := unmarshalPullData(rd, &result)
+	err := s.unmarshalPullData(rd, &result)
 	if err != nil {
 		return pullResponse{}, err
 	}
 
-	if result.Error == loggedOutError {
-		return pullResponse{}, ErrLoggedOut
-	} else if result.Error > 0 {
-		return pullResponse{}, ErrUnknown
+	err = s.responseError(result.Error)
+	if err != nil {
+		return pullResponse{}, err
 	}
 
 	return result, nil
 }
 
-func unmarshalPullData(rd io.Reader, to interface{}) error {
+func (s *Session) unmarshalPullData(rd io.Reader, to interface{}) error {
 	data, err := ioutil.ReadAll(rd)
 	if err != nil {
 		return err
 	}
 
-	if os.Getenv("MDEBUG") == "true" {
-		if len(data) > 10000 {
-			log.Println("debug response size:", len(data))
-		} else {
-			log.Println("debug response: " + string(data))
-		}
+	id := requestID(rd)
+	if len(data) > 10000 {
+		s.logger.Debug("response body", "request_id", id, "size", len(data))
+	} else {
+		s.logger.Debug("response body", "request_id", id,
+			"body", redactResponse(data))
 	}
 
 	startPos := bytes.IndexByte(data, '{')
@@ -47,9 +43,48 @@ func unmarshalPullData(rd io.Reader, to interface{}) error {
 
 	err = json.Unmarshal(data[startPos:], to)
 	if err != nil {
-		fmt.Println(string(data))
+		s.logger.Warn("failed to parse response", "request_id", id,
+			"error", err, "body", redactResponse(data))
 		return err
 	}
 
 	return nil
 }
+
+// flexibleBool is a boolean that may be encoded as either a JSON boolean or
+// number, or null.
+type flexibleBool bool
+
+func (f *flexibleBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "null", "false", "0", `""`, `"0"`:
+		*f = false
+	default:
+		*f = true
+	}
+
+	return nil
+}
+
+// flexibleID is an ID that may be encoded as either a JSON string or number,
+// or null.
+type flexibleID string
+
+func (f *flexibleID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*f = ""
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var id string
+		err := json.Unmarshal(data, &id)
+		*f = flexibleID(id)
+		return err
+	}
+
+	var id json.Number
+	err := json.Unmarshal(data, &id)
+	*f = flexibleID(id)
+	return err
+}
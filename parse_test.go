@@ -0,0 +1,69 @@
+package messenger
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFlexibleID(t *testing.T) {
+	tests := []struct {
+		data string
+		want flexibleID
+		err  bool
+	}{
+		{`"1000"`, "1000", false},
+		{`1000`, "1000", false},
+		{`100000000000000001`, "100000000000000001", false},
+		{`null`, "", false},
+		{`""`, "", false},
+		{`true`, "", true},
+		{`"1000`, "", true},
+	}
+
+	for _, test := range tests {
+		var id flexibleID
+		err := json.Unmarshal([]byte(test.data), &id)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.data)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.data, err)
+		}
+
+		if id != test.want {
+			t.Errorf("%s: got %q, want %q", test.data, id, test.want)
+		}
+	}
+}
+
+func TestFlexibleBool(t *testing.T) {
+	tests := []struct {
+		data string
+		want flexibleBool
+	}{
+		{`true`, true},
+		{`false`, false},
+		{`1`, true},
+		{`0`, false},
+		{`"1"`, true},
+		{`"0"`, false},
+		{`""`, false},
+		{`null`, false},
+	}
+
+	for _, test := range tests {
+		var b flexibleBool
+		err := json.Unmarshal([]byte(test.data), &b)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.data, err)
+		}
+
+		if b != test.want {
+			t.Errorf("%s: got %v, want %v", test.data, b, test.want)
+		}
+	}
+}
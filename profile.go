@@ -1,21 +1,47 @@
 package messenger
 
 import (
+	"context"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
+// Profile types returned in UserProfile.Type.
+const (
+	ProfileTypeUser  = "user"
+	ProfileTypePage  = "page"
+	ProfileTypeGroup = "group"
+)
+
 // UserProfile represents the profile information for a user.
 type UserProfile struct {
-	UserID    string `json:"id"`
-	Name      string `json:"name"`
-	FirstName string `json:"firstName"`
-	Vanity    string `json:"vanity"`
-	IsFriend  bool   `json:"is_friend"`
+	UserID        string `json:"id"`
+	Name          string `json:"name"`
+	FirstName     string `json:"firstName"`
+	AlternateName string `json:"alternateName"`
+	Vanity        string `json:"vanity"`
+	IsFriend      bool   `json:"is_friend"`
+	// PictureURL is the URL of the user's profile picture thumbnail. Use
+	// ProfilePicture to fetch larger sizes.
+	PictureURL string `json:"thumbSrc"`
+	ProfileURL string `json:"uri"`
+	// Gender is 1 for female, 2 for male, and 0 or other values if unknown.
+	Gender int `json:"gender"`
+	// Type is one of ProfileTypeUser, ProfileTypePage or ProfileTypeGroup,
+	// or another type of profile.
+	Type         string   `json:"type"`
+	IsBirthday   bool     `json:"is_birthday"`
+	SearchTokens []string `json:"searchTokens"`
 }
 
-type singleUserResponse struct {
+// maxProfileBatch is the maximum number of profiles requested from the
+// server at once.
+const maxProfileBatch = 50
+
+type profilesResponse struct {
 	Payload struct {
 		Profiles map[string]UserProfile `json:"profiles"`
 	} `json:"payload"`
@@ -24,40 +50,98 @@ type singleUserResponse struct {
 
 // UserProfileInfo returns the user's profile given their ID.
 func (s *Session) UserProfileInfo(userID string) (UserProfile, error) {
+	profiles, err := s.UserProfiles(context.Background(), []string{userID})
+	if err != nil {
+		return UserProfile{}, err
+	}
+
+	profile, found := profiles[userID]
+	if !found {
+		return UserProfile{}, ParseError{"could not find userID in response"}
+	}
+
+	return profile, nil
+}
+
+// UserProfiles returns the profiles of the users with the given IDs as a
+// map indexed by the user's ID. Profiles are requested in batches, and are
+// served from the profile cache if it's enabled. IDs of users that could not
+// be found are missing from the map.
+func (s *Session) UserProfiles(ctx context.Context,
+	ids []string) (map[string]UserProfile, error) {
+	profiles := make(map[string]UserProfile)
+	seen := make(map[string]bool)
+	var missing []string
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		profile, found := s.profiles.Load().get(id)
+		if found {
+			profiles[id] = profile
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	for len(missing) > 0 {
+		batch := missing
+		if len(batch) > maxProfileBatch {
+			batch = batch[:maxProfileBatch]
+		}
+		missing = missing[len(batch):]
+
+		fetched, err := s.fetchProfiles(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		cache := s.profiles.Load()
+		for id, profile := range fetched {
+			profiles[id] = profile
+			cache.put(profile)
+		}
+	}
+
+	return profiles, nil
+}
+
+func (s *Session) fetchProfiles(ctx context.Context,
+	ids []string) (map[string]UserProfile, error) {
 	form := make(url.Values)
-	form.Set("ids[0]", userID)
+	for i, id := range ids {
+		form.Set("ids["+strconv.Itoa(i)+"]", id)
+	}
 	form = s.addFormMeta(form)
 
 	req, _ := http.NewRequest(http.MethodPost, profileURL,
 		strings.NewReader(form.Encode()))
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
 
 	resp, err := s.doRequest(req)
 	if err != nil {
-		return UserProfile{}, err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
-	var singleUserResp singleUserResponse
-	err = unmarshalPullData(resp.Body, &singleUserResp)
+	var profilesResp profilesResponse
+	err = s.unmarshalPullData(resp.Body, &profilesResp)
 	if err != nil {
-		return UserProfile{}, err
-	}
-
-	if singleUserResp.Error == loggedOutError {
-		return UserProfile{}, ErrLoggedOut
-	} else if singleUserResp.Error > 0 {
-		return UserProfile{}, ErrUnknown
+		return nil, err
 	}
 
-	profile, found := singleUserResp.Payload.Profiles[userID]
-	if !found {
-		return UserProfile{}, ParseError{"could not find userID in response"}
+	err = s.responseError(profilesResp.Error)
+	if err != nil {
+		return nil, err
 	}
 
-	return profile, nil
+	return profilesResp.Payload.Profiles, nil
 }
 
 type allUsersResponse struct {
@@ -66,15 +150,22 @@ type allUsersResponse struct {
 }
 
 // AllUserProfileInfo returns all the users' profiles in the session's friend
-// list as a map indexed by the user's ID.
+// list as a map indexed by the user's ID. The profiles are served from the
+// profile cache if it's enabled.
 func (s *Session) AllUserProfileInfo() (map[string]UserProfile, error) {
+	cache := s.profiles.Load()
+	profiles, found := cache.getFriends()
+	if found {
+		return profiles, nil
+	}
+
 	form := make(url.Values)
 	form.Set("viewer", s.userID)
 	form = s.addFormMeta(form)
 
 	req, _ := http.NewRequest(http.MethodPost, allProfileURL,
 		strings.NewReader(form.Encode()))
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 
 	resp, err := s.doRequest(req)
 	if err != nil {
@@ -84,16 +175,69 @@ func (s *Session) AllUserProfileInfo() (map[string]UserProfile, error) {
 	defer resp.Body.Close()
 
 	var allUsersResp allUsersResponse
-	err = unmarshalPullData(resp.Body, &allUsersResp)
+	err = s.unmarshalPullData(resp.Body, &allUsersResp)
 	if err != nil {
 		return nil, err
 	}
 
-	if allUsersResp.Error == loggedOutError {
-		return nil, ErrLoggedOut
-	} else if allUsersResp.Error > 0 {
-		return nil, ErrUnknown
+	err = s.responseError(allUsersResp.Error)
+	if err != nil {
+		return nil, err
 	}
 
+	cache.putFriends(allUsersResp.Payload)
+
 	return allUsersResp.Payload, nil
 }
+
+// maxPictureRedirects is the maximum number of redirects followed when
+// fetching a profile picture.
+const maxPictureRedirects = 3
+
+// ProfilePicture returns the image data of the user's profile picture,
+// scaled to approximately size by size pixels.
+func (s *Session) ProfilePicture(ctx context.Context, userID string,
+	size int) ([]byte, error) {
+	form := make(url.Values)
+	form.Set("width", strconv.Itoa(size))
+	form.Set("height", strconv.Itoa(size))
+	pictureURL := graphURL + url.PathEscape(userID) + "/picture?" +
+		form.Encode()
+
+	for i := 0; i <= maxPictureRedirects; i++ {
+		req, err := http.NewRequest(http.MethodGet, pictureURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header = s.defaultHeader()
+		req = req.WithContext(ctx)
+
+		resp, err := s.doRequest(req)
+		if err != nil {
+			urlErr, ok := err.(*url.Error)
+			if !ok || urlErr.Err != errNoRedirects {
+				return nil, err
+			}
+
+			redirURL, err := resp.Location()
+			if err != nil {
+				return nil, err
+			}
+
+			pictureURL = redirURL.String()
+			continue
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, ParseError{"unexpected status fetching profile " +
+				"picture: " + resp.Status}
+		}
+
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	return nil, ParseError{"too many redirects fetching profile picture"}
+}
@@ -0,0 +1,152 @@
+package messenger
+
+import (
+	"sync"
+	"time"
+)
+
+type cachedProfile struct {
+	profile UserProfile
+	expires time.Time
+}
+
+// profileCache caches user profiles for a fixed duration. A nil
+// profileCache caches nothing.
+type profileCache struct {
+	ttl      time.Duration
+	profiles map[string]cachedProfile
+	// nextSweep is when expired profiles are next removed from the cache.
+	nextSweep time.Time
+
+	// friends are the IDs of the profiles returned by AllUserProfileInfo,
+	// which expire at friendsExpire.
+	friends       []string
+	friendsExpire time.Time
+
+	mutex *sync.Mutex
+}
+
+// EnableProfileCache enables caching of user profiles returned by
+// UserProfileInfo, UserProfiles and AllUserProfileInfo for the given
+// duration. A duration of 0 or less disables the cache.
+func (s *Session) EnableProfileCache(ttl time.Duration) {
+	if ttl <= 0 {
+		s.profiles.Store(nil)
+		return
+	}
+
+	s.profiles.Store(&profileCache{
+		ttl:       ttl,
+		profiles:  make(map[string]cachedProfile),
+		nextSweep: time.Now().Add(ttl),
+		mutex:     new(sync.Mutex),
+	})
+}
+
+func (c *profileCache) get(userID string) (UserProfile, bool) {
+	if c == nil {
+		return UserProfile{}, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.getLocked(userID, time.Now())
+}
+
+func (c *profileCache) getLocked(userID string,
+	now time.Time) (UserProfile, bool) {
+	cached, found := c.profiles[userID]
+	if !found {
+		return UserProfile{}, false
+	}
+
+	if now.After(cached.expires) {
+		delete(c.profiles, userID)
+		return UserProfile{}, false
+	}
+
+	return cached.profile, true
+}
+
+func (c *profileCache) put(profile UserProfile) {
+	if c == nil || profile.UserID == "" {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.putLocked(profile, time.Now())
+}
+
+func (c *profileCache) putLocked(profile UserProfile, now time.Time) {
+	if !now.Before(c.nextSweep) {
+		c.sweep(now)
+	}
+
+	c.profiles[profile.UserID] = cachedProfile{
+		profile: profile,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// sweep removes expired profiles from the cache, and must be called with
+// the mutex held.
+func (c *profileCache) sweep(now time.Time) {
+	for userID, cached := range c.profiles {
+		if now.After(cached.expires) {
+			delete(c.profiles, userID)
+		}
+	}
+
+	c.nextSweep = now.Add(c.ttl)
+}
+
+// getFriends returns the cached profiles returned by AllUserProfileInfo. If
+// any of them have expired, nothing is returned.
+func (c *profileCache) getFriends() (map[string]UserProfile, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if c.friends == nil || now.After(c.friendsExpire) {
+		return nil, false
+	}
+
+	profiles := make(map[string]UserProfile)
+	for _, userID := range c.friends {
+		profile, found := c.getLocked(userID, now)
+		if !found {
+			return nil, false
+		}
+
+		profiles[userID] = profile
+	}
+
+	return profiles, true
+}
+
+// putFriends caches the profiles returned by AllUserProfileInfo.
+func (c *profileCache) putFriends(profiles map[string]UserProfile) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	c.friends = make([]string, 0, len(profiles))
+	for userID, profile := range profiles {
+		profile.UserID = userID
+		c.putLocked(profile, now)
+		c.friends = append(c.friends, userID)
+	}
+
+	c.friendsExpire = now.Add(c.ttl)
+}
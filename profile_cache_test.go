@@ -0,0 +1,72 @@
+package messenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfileCacheSweep(t *testing.T) {
+	s := NewSession()
+	s.EnableProfileCache(time.Minute)
+	cache := s.profiles.Load()
+
+	now := time.Now()
+	cache.putLocked(UserProfile{UserID: "1"}, now)
+	cache.putLocked(UserProfile{UserID: "2"}, now.Add(30*time.Second))
+
+	if _, found := cache.get("1"); !found {
+		t.Error("profile not cached")
+	}
+
+	// The sweep is due once the ttl has passed, which removes profile 1.
+	cache.putLocked(UserProfile{UserID: "3"}, now.Add(90*time.Second))
+	if len(cache.profiles) != 2 {
+		t.Errorf("expected 2 cached profiles after sweep, got %d",
+			len(cache.profiles))
+	}
+
+	if _, found := cache.profiles["1"]; found {
+		t.Error("expired profile wasn't swept")
+	}
+}
+
+func TestProfileCacheFriends(t *testing.T) {
+	s := NewSession()
+	if _, found := s.profiles.Load().getFriends(); found {
+		t.Error("disabled cache returned friends")
+	}
+
+	s.EnableProfileCache(time.Minute)
+	cache := s.profiles.Load()
+	if _, found := cache.getFriends(); found {
+		t.Error("empty cache returned friends")
+	}
+
+	cache.putFriends(map[string]UserProfile{
+		"1": {Name: "Alice"},
+		"2": {Name: "Bob"},
+	})
+
+	friends, found := cache.getFriends()
+	if !found || len(friends) != 2 || friends["1"].Name != "Alice" ||
+		friends["1"].UserID != "1" {
+		t.Errorf("unexpected cached friends: %v", friends)
+	}
+
+	if profile, found := cache.get("2"); !found || profile.Name != "Bob" {
+		t.Error("friend profiles aren't shared with the profile cache")
+	}
+
+	cache.mutex.Lock()
+	delete(cache.profiles, "2")
+	cache.mutex.Unlock()
+
+	if _, found := cache.getFriends(); found {
+		t.Error("friends returned with an expired profile")
+	}
+
+	s.EnableProfileCache(0)
+	if s.profiles.Load() != nil {
+		t.Error("cache wasn't disabled")
+	}
+}
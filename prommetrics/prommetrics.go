@@ -0,0 +1,159 @@
+// Package prommetrics provides a Prometheus collector that records the
+// metrics of a messenger.Session.
+//
+//	collector := prommetrics.New("messenger", prometheus.Labels{"account": "bot"})
+//	prometheus.MustRegister(collector)
+//	session.SetMetrics(collector)
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/1lann/messenger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector that implements messenger.Metrics.
+type Collector struct {
+	pullLatency      *prometheus.HistogramVec
+	reconnects       prometheus.Counter
+	listenErrors     *prometheus.CounterVec
+	messagesReceived *prometheus.CounterVec
+	messagesSent     *prometheus.CounterVec
+	sendLatency      *prometheus.HistogramVec
+	serverErrors     *prometheus.CounterVec
+	duplicates       prometheus.Counter
+}
+
+var _ messenger.Metrics = (*Collector)(nil)
+
+// New returns a new Collector whose metrics are prefixed with namespace and
+// have the given constant labels, which can be used to distinguish
+// sessions.
+func New(namespace string, labels prometheus.Labels) *Collector {
+	return &Collector{
+		pullLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "pull_duration_seconds",
+			Help:        "Latency of long polls to the chat server.",
+			ConstLabels: labels,
+			Buckets:     []float64{0.1, 0.5, 1, 5, 10, 30, 60, 70},
+		}, []string{"result"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "reconnects_total",
+			Help:        "Number of reconnects and reloads requested by the chat server.",
+			ConstLabels: labels,
+		}),
+		listenErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "listen_errors_total",
+			Help:        "Number of listen errors by operation.",
+			ConstLabels: labels,
+		}, []string{"op"}),
+		messagesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "messages_received_total",
+			Help:        "Number of messages received by thread type.",
+			ConstLabels: labels,
+		}, []string{"thread_type"}),
+		messagesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "messages_sent_total",
+			Help:        "Number of messages sent by thread type and result.",
+			ConstLabels: labels,
+		}, []string{"thread_type", "result"}),
+		sendLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        "send_duration_seconds",
+			Help:        "Latency of sending messages by thread type.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"thread_type"}),
+		serverErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "server_errors_total",
+			Help:        "Number of errors returned by the server by error.",
+			ConstLabels: labels,
+		}, []string{"error"}),
+		duplicates: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "duplicate_messages_total",
+			Help:        "Number of duplicate messages suppressed.",
+			ConstLabels: labels,
+		}),
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "success"
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.pullLatency.Describe(ch)
+	c.reconnects.Describe(ch)
+	c.listenErrors.Describe(ch)
+	c.messagesReceived.Describe(ch)
+	c.messagesSent.Describe(ch)
+	c.sendLatency.Describe(ch)
+	c.serverErrors.Describe(ch)
+	c.duplicates.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.pullLatency.Collect(ch)
+	c.reconnects.Collect(ch)
+	c.listenErrors.Collect(ch)
+	c.messagesReceived.Collect(ch)
+	c.messagesSent.Collect(ch)
+	c.sendLatency.Collect(ch)
+	c.serverErrors.Collect(ch)
+	c.duplicates.Collect(ch)
+}
+
+// PullCompleted implements messenger.Metrics.
+func (c *Collector) PullCompleted(latency time.Duration, err error) {
+	c.pullLatency.WithLabelValues(result(err)).Observe(latency.Seconds())
+}
+
+// Reconnected implements messenger.Metrics.
+func (c *Collector) Reconnected() {
+	c.reconnects.Inc()
+}
+
+// ListenError implements messenger.Metrics.
+func (c *Collector) ListenError(op string) {
+	c.listenErrors.WithLabelValues(op).Inc()
+}
+
+// MessageReceived implements messenger.Metrics.
+func (c *Collector) MessageReceived(threadType string) {
+	c.messagesReceived.WithLabelValues(threadType).Inc()
+}
+
+// MessageSent implements messenger.Metrics.
+func (c *Collector) MessageSent(threadType string, latency time.Duration, err error) {
+	c.messagesSent.WithLabelValues(threadType, result(err)).Inc()
+	c.sendLatency.WithLabelValues(threadType).Observe(latency.Seconds())
+}
+
+// ServerError implements messenger.Metrics.
+func (c *Collector) ServerError(err error) {
+	label := "unknown"
+	if err == messenger.ErrLoggedOut {
+		label = "logged_out"
+	}
+
+	c.serverErrors.WithLabelValues(label).Inc()
+}
+
+// DuplicateDropped implements messenger.Metrics.
+func (c *Collector) DuplicateDropped() {
+	c.duplicates.Inc()
+}
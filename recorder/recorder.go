@@ -0,0 +1,293 @@
+// Package recorder provides an http.RoundTripper that records the requests
+// and responses made by a messenger.Session to a cassette, and one that
+// replays a cassette, allowing interactions with Facebook to be tested
+// offline. Set either on a session with Session.SetTransport.
+//
+// Cassettes are JSON lines, with one interaction per line. Cookie values
+// (other than c_user), fb_dtsg, sticky and similar tokens, and login
+// credentials are redacted before they're written. Message contents are
+// kept so that replayed messages can be checked.
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoInteraction is returned by a Replayer if there are no more recorded
+// interactions for a request.
+var ErrNoInteraction = errors.New("recorder: no recorded interaction for request")
+
+const redacted = "REDACTED"
+
+// Interaction represents a recorded request and its response.
+type Interaction struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"request_header"`
+	RequestBody    string      `json:"request_body"`
+	StatusCode     int         `json:"status_code"`
+	ResponseHeader http.Header `json:"response_header"`
+	ResponseBody   string      `json:"response_body"`
+}
+
+// key returns the key that interactions are matched by. Query parameters
+// are ignored as they include timestamps and random IDs.
+func key(method string, u *url.URL) string {
+	return method + " " + u.Scheme + "://" + u.Host + u.Path
+}
+
+// Recorder is an http.RoundTripper that records every interaction to a
+// cassette.
+type Recorder struct {
+	next  http.RoundTripper
+	enc   *json.Encoder
+	mutex *sync.Mutex
+}
+
+// New returns a Recorder that writes interactions to w and performs
+// requests with next. If next is nil, http.DefaultTransport is used.
+func New(w io.Writer, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Recorder{
+		next:  next,
+		enc:   json.NewEncoder(w),
+		mutex: new(sync.Mutex),
+	}
+}
+
+// RoundTrip performs the request and records the interaction.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeader:  redactHeader(req.Header),
+		RequestBody:    redactForm(string(reqBody)),
+		StatusCode:     resp.StatusCode,
+		ResponseHeader: redactHeader(resp.Header),
+		ResponseBody:   redactBody(respBody),
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	err = r.enc.Encode(interaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that serves responses from a cassette.
+// Requests are matched to interactions by their method, host and path, and
+// interactions with the same match are served in the order they were
+// recorded.
+type Replayer struct {
+	interactions map[string][]Interaction
+	mutex        *sync.Mutex
+}
+
+// NewReplayer returns a Replayer that serves the interactions in the
+// cassette read from rd.
+func NewReplayer(rd io.Reader) (*Replayer, error) {
+	r := &Replayer{
+		interactions: make(map[string][]Interaction),
+		mutex:        new(sync.Mutex),
+	}
+
+	dec := json.NewDecoder(rd)
+	for {
+		var interaction Interaction
+		err := dec.Decode(&interaction)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		u, err := url.Parse(interaction.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		k := key(interaction.Method, u)
+		r.interactions[k] = append(r.interactions[k], interaction)
+	}
+
+	return r, nil
+}
+
+// RoundTrip returns the next recorded response for the request, or
+// ErrNoInteraction if there is none.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	k := key(req.Method, req.URL)
+
+	r.mutex.Lock()
+	interactions := r.interactions[k]
+	if len(interactions) == 0 {
+		r.mutex.Unlock()
+		return nil, ErrNoInteraction
+	}
+
+	interaction := interactions[0]
+	r.interactions[k] = interactions[1:]
+	r.mutex.Unlock()
+
+	header := interaction.ResponseHeader
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status: strconv.Itoa(interaction.StatusCode) + " " +
+			http.StatusText(interaction.StatusCode),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(interaction.ResponseBody)),
+		ContentLength: int64(len(interaction.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+// Remaining returns the number of recorded interactions that haven't been
+// served.
+func (r *Replayer) Remaining() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	remaining := 0
+	for _, interactions := range r.interactions {
+		remaining += len(interactions)
+	}
+
+	return remaining
+}
+
+// redactedParams are the query parameters and form fields whose values are
+// redacted.
+var redactedParams = []string{"fb_dtsg", "ttstamp", "h", "sticky_token",
+	"pass", "email", "approvals_code"}
+
+func redactValues(values url.Values) url.Values {
+	for _, param := range redactedParams {
+		if _, found := values[param]; found {
+			values.Set(param, redacted)
+		}
+	}
+
+	return values
+}
+
+func redactURL(u *url.URL) string {
+	result := *u
+	result.RawQuery = redactValues(u.Query()).Encode()
+	return result.String()
+}
+
+func redactForm(form string) string {
+	values, err := url.ParseQuery(form)
+	if err != nil {
+		return redacted
+	}
+
+	return redactValues(values).Encode()
+}
+
+// cookieValuePattern matches the value of each cookie in Cookie and
+// Set-Cookie headers.
+var cookieValuePattern = regexp.MustCompile(`(^|;\s*)([^=;\s]+)=([^;]*)`)
+
+func redactCookies(header string, setCookie bool) string {
+	first := true
+	return cookieValuePattern.ReplaceAllStringFunc(header, func(match string) string {
+		// Only the first pair in a Set-Cookie header is the cookie, the rest
+		// are attributes.
+		if setCookie && !first {
+			return match
+		}
+		first = false
+
+		parts := cookieValuePattern.FindStringSubmatch(match)
+		if parts[2] == "c_user" {
+			return match
+		}
+
+		return parts[1] + parts[2] + "=" + redacted
+	})
+}
+
+func redactHeader(header http.Header) http.Header {
+	result := make(http.Header)
+	for name, values := range header {
+		for _, value := range values {
+			switch http.CanonicalHeaderKey(name) {
+			case "Cookie":
+				value = redactCookies(value, false)
+			case "Set-Cookie":
+				value = redactCookies(value, true)
+			}
+
+			result.Add(name, value)
+		}
+	}
+
+	return result
+}
+
+var bodyPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`("(?:fb_dtsg|token|dtsg|sticky)"\s*:\s*")(?:[^"\\]|\\.)*(")`),
+	regexp.MustCompile(`(name=\\?"(?:fb_dtsg|h)\\?" value=\\?")[^"\\]*(\\?")`),
+}
+
+func redactBody(body []byte) string {
+	for _, pattern := range bodyPatterns {
+		body = pattern.ReplaceAll(body, []byte("${1}"+redacted+"${2}"))
+	}
+
+	return string(body)
+}
@@ -0,0 +1,107 @@
+package recorder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type stubTransport struct {
+	header http.Header
+	body   string
+}
+
+func (t stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     t.header,
+		Body:       ioutil.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	body := `for (;;); {"t":"lb","lb_info":{"sticky":"sticky-secret","pool":"atn2c06_chat-proxy"},` +
+		`"ms":[{"type":"delta","delta":{"body":"hello"}}]}`
+	stub := stubTransport{
+		header: http.Header{
+			"Set-Cookie": []string{
+				"xs=xs-secret; expires=Fri, 01 Jan 2100 00:00:00 GMT; path=/; domain=.facebook.com",
+				"c_user=1000; path=/; domain=.facebook.com",
+			},
+		},
+		body: body,
+	}
+
+	var cassette bytes.Buffer
+	rec := New(&cassette, stub)
+
+	form := url.Values{
+		"fb_dtsg": []string{"dtsg-secret"},
+		"body":    []string{"hi there"},
+	}
+	req, _ := http.NewRequest(http.MethodPost,
+		"https://www.facebook.com/messaging/send/?sticky_token=url-secret&seq=1",
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Cookie", "c_user=1000; xs=xs-secret")
+
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadAll(resp.Body)
+	if string(data) != body {
+		t.Errorf("recorded response body changed: %s", data)
+	}
+
+	recorded := cassette.String()
+	for _, secret := range []string{"xs-secret", "dtsg-secret",
+		"url-secret", "sticky-secret"} {
+		if strings.Contains(recorded, secret) {
+			t.Errorf("cassette contains %q: %s", secret, recorded)
+		}
+	}
+
+	for _, kept := range []string{"c_user=1000", `\"body\":\"hello\"`,
+		"body=hi+there", "atn2c06_chat-proxy"} {
+		if !strings.Contains(recorded, kept) {
+			t.Errorf("cassette is missing %q: %s", kept, recorded)
+		}
+	}
+
+	replayer, err := NewReplayer(strings.NewReader(recorded))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Query parameters aren't matched.
+	req, _ = http.NewRequest(http.MethodPost,
+		"https://www.facebook.com/messaging/send/?seq=2", nil)
+	resp, err = replayer.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ = ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(data), `"body":"hello"`) {
+		t.Errorf("unexpected replayed body: %s", data)
+	}
+
+	if len(resp.Header["Set-Cookie"]) != 2 {
+		t.Errorf("expected 2 replayed cookies, got %v", resp.Header)
+	}
+
+	if replayer.Remaining() != 0 {
+		t.Errorf("expected no remaining interactions, got %d",
+			replayer.Remaining())
+	}
+
+	_, err = replayer.RoundTrip(req)
+	if err != ErrNoInteraction {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package messenger
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/1lann/messenger/recorder"
+)
+
+// newReplaySession returns a session connected as user 1000 that serves
+// requests from the cassette at path.
+func newReplaySession(t *testing.T, path string) (*Session, *recorder.Replayer) {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	replayer, err := recorder.NewReplayer(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSession()
+	s.SetLogger(nil)
+	s.SetTransport(replayer)
+	s.userID = "1000"
+	s.clientID = "abcd1234"
+	s.l.form = s.newPullForm()
+	s.l.form.seq = 2
+
+	return s, replayer
+}
+
+func TestReplayPullAndSend(t *testing.T) {
+	s, replayer := newReplaySession(t, "testdata/replay.jsonl")
+
+	messages := make(chan *Message, 2)
+	s.OnMessage(func(msg *Message) {
+		messages <- msg
+	})
+	s.checkListeners()
+
+	req, _ := http.NewRequest(http.MethodGet,
+		chatURL+s.l.form.form().Encode(), nil)
+	req.Header = s.defaultHeader()
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	respInfo, err := s.parseResponse(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if respInfo.Type != "msg" || respInfo.Seq != 3 {
+		t.Errorf("unexpected pull response: %+v", respInfo)
+	}
+
+	s.processPull(respInfo)
+
+	var msg *Message
+	select {
+	case msg = <-messages:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+
+	want := &Message{
+		FromUserID: "1001",
+		Thread: Thread{
+			ThreadID: "2000",
+			IsGroup:  true,
+		},
+		Body: "@Alice 👋 hello",
+		Attachments: []Attachment{{
+			Type:      AttachmentSticker,
+			StickerID: StickerLikeSmall,
+			URL:       "https://scontent.xx.fbcdn.net/sticker.png",
+		}},
+		Mentions: []Mention{{
+			UserID: "1002",
+			Offset: 0,
+			Length: 6,
+		}},
+		MessageID:       "mid.$gAAA",
+		offlineThreadID: "6500000000000000001",
+	}
+
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("got message %+v, want %+v", msg, want)
+	}
+
+	select {
+	case msg = <-messages:
+		t.Errorf("duplicate message delivered: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	reply := s.NewMessageWithThread(msg.Thread)
+	reply.Body, reply.Mentions = FormatMentions("@{1001} hi",
+		map[string]string{"1001": "Alice"})
+
+	messageID, err := s.SendMessage(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if messageID != "mid.$gBBB" {
+		t.Errorf("got message ID %q, want %q", messageID, "mid.$gBBB")
+	}
+
+	if replayer.Remaining() != 0 {
+		t.Errorf("%d interactions weren't replayed", replayer.Remaining())
+	}
+}
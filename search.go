@@ -0,0 +1,180 @@
+package messenger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUserNotFound is returned by ResolveUser if no user matches.
+var ErrUserNotFound = errors.New("messenger: user not found")
+
+type searchEntry struct {
+	UserID    flexibleID `json:"uid"`
+	Name      string     `json:"text"`
+	Photo     string     `json:"photo"`
+	Path      string     `json:"path"`
+	Type      string     `json:"type"`
+	IndexRank int        `json:"index_rank"`
+}
+
+type searchUsersResponse struct {
+	Payload struct {
+		Entries []searchEntry `json:"entries"`
+	} `json:"payload"`
+	Error int `json:"error"`
+}
+
+// profile converts the search entry into a user profile. The vanity is
+// derived from the profile path, which is the vanity for users who have
+// one.
+func (e searchEntry) profile() UserProfile {
+	profile := UserProfile{
+		UserID:     string(e.UserID),
+		Name:       e.Name,
+		PictureURL: e.Photo,
+		Type:       e.Type,
+	}
+
+	if e.Path != "" {
+		profile.ProfileURL = facebookOrigin + e.Path
+		vanity := strings.Trim(e.Path, "/")
+		if vanity != "" && !strings.ContainsAny(vanity, "/?.") {
+			profile.Vanity = vanity
+		}
+	}
+
+	return profile
+}
+
+// SearchUsers searches for users, pages and groups matching the query, and
+// returns them ranked by relevance. Only the UserID, Name, Vanity,
+// PictureURL, ProfileURL and Type fields of the profiles are set.
+func (s *Session) SearchUsers(ctx context.Context, query string) ([]UserProfile, error) {
+	form := make(url.Values)
+	form.Set("value", strings.ToLower(query))
+	form.Set("viewer", s.userID)
+	form.Set("rsp", "search")
+	form.Set("context", "search")
+	form.Set("path", "/home.php")
+	form.Set("request_id", strconv.FormatInt(largeRandomNumber(), 16))
+	form = s.addFormMeta(form)
+
+	req, _ := http.NewRequest(http.MethodGet, searchUsersURL+form.Encode(), nil)
+	req.Header = s.defaultHeader()
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var searchResp searchUsersResponse
+	err = s.unmarshalPullData(resp.Body, &searchResp)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.responseError(searchResp.Error)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := searchResp.Payload.Entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].IndexRank < entries[j].IndexRank
+	})
+
+	profiles := make([]UserProfile, 0, len(entries))
+	for _, entry := range entries {
+		profiles = append(profiles, entry.profile())
+	}
+
+	return profiles, nil
+}
+
+type searchThreadsResponse struct {
+	Payload struct {
+		MercuryPayload struct {
+			Threads []mercuryThread `json:"threads"`
+		} `json:"mercury_payload"`
+	} `json:"payload"`
+	Error int `json:"error"`
+}
+
+// SearchThreads searches for threads whose name or participants match the
+// query, and returns them ranked by relevance.
+func (s *Session) SearchThreads(ctx context.Context, query string) ([]ThreadInfo, error) {
+	form := make(url.Values)
+	form.Set("client", "web_messenger")
+	form.Set("query", query)
+	form.Set("offset", "0")
+	form.Set("limit", "21")
+	form.Set("index", "fbid")
+	form = s.addFormMeta(form)
+
+	req, _ := http.NewRequest(http.MethodPost, searchThreadsURL,
+		strings.NewReader(form.Encode()))
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var searchResp searchThreadsResponse
+	err = s.unmarshalPullData(resp.Body, &searchResp)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.responseError(searchResp.Error)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := searchResp.Payload.MercuryPayload.Threads
+	infos := make([]ThreadInfo, 0, len(threads))
+	for _, thread := range threads {
+		infos = append(infos, thread.info())
+	}
+
+	return infos, nil
+}
+
+// ResolveUser returns the profile of the user with the given vanity
+// (username), which may be prefixed with "@", or otherwise the user whose
+// name exactly matches. ErrUserNotFound is returned if there is no such
+// user.
+func (s *Session) ResolveUser(ctx context.Context, vanityOrName string) (UserProfile, error) {
+	query := strings.TrimPrefix(strings.TrimSpace(vanityOrName), "@")
+
+	profiles, err := s.SearchUsers(ctx, query)
+	if err != nil {
+		return UserProfile{}, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Vanity != "" && strings.EqualFold(profile.Vanity, query) {
+			return profile, nil
+		}
+	}
+
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.Name, query) {
+			return profile, nil
+		}
+	}
+
+	return UserProfile{}, ErrUserNotFound
+}
@@ -9,20 +9,43 @@ import (
 	"time"
 )
 
-// Attachment represents an attachment
+// AttachmentType represents the type of a received attachment.
+type AttachmentType string
+
+// Types of received attachments.
+const (
+	AttachmentSticker AttachmentType = "sticker"
+)
+
+// Attachment represents an attachment. Type, StickerID and URL are only set
+// on received attachments.
 type Attachment struct {
 	Name string
 	Data io.Reader
+
+	Type      AttachmentType
+	StickerID string
+	URL       string
 }
 
 // Message represents a message object.
 type Message struct {
-	FromUserID      string
-	Thread          Thread
-	Body            string
-	Attachments     []Attachment
-	MessageID       string
-	offlineThreadID string
+	FromUserID  string
+	Thread      Thread
+	Body        string
+	Attachments []Attachment
+	// StickerID is the ID of the sticker to send instead of a body, such as
+	// StickerLikeSmall. Received stickers are attachments of type
+	// AttachmentSticker.
+	StickerID string
+	// Mentions are the users mentioned in the body, such as those returned
+	// by FormatMentions.
+	Mentions  []Mention
+	MessageID string
+	// IsMessageRequest is whether the message was received as a message
+	// request. See AcceptMessageRequest.
+	IsMessageRequest bool
+	offlineThreadID  string
 }
 
 // NewMessageWithThread creates a new message for the given thread.
@@ -33,18 +56,33 @@ func (s *Session) NewMessageWithThread(thread Thread) *Message {
 	}
 }
 
+// OfflineThreadID returns the client generated ID of the message. It is set
+// on messages created with NewMessageWithThread and on echoed messages, and
+// can be used to match an echo to the message that was sent.
+func (m *Message) OfflineThreadID() string {
+	return m.offlineThreadID
+}
+
 type sendResponse struct {
 	Payload pullMessage `json:"payload"`
 	Error   int         `json:"error"`
 }
 
-// SendMessage sends the message to the session. Only the Thread, Body and
-// Attachments fields are used for sending. The message ID and error is returned.
+// SendMessage sends the message to the session. Only the Thread, Body,
+// StickerID, Mentions and Attachments fields are used for sending. The
+// message ID and error is returned.
 //
 // TODO: Sending does not support attachments yet.
 func (s *Session) SendMessage(msg *Message) (string, error) {
+	start := time.Now()
+	messageID, err := s.sendMessage(msg)
+	s.metrics.MessageSent(threadType(msg.Thread), time.Since(start), err)
+	return messageID, err
+}
+
+func (s *Session) sendMessage(msg *Message) (string, error) {
 	hasAttachment := "false"
-	if len(msg.Attachments) > 0 {
+	if len(msg.Attachments) > 0 || msg.StickerID != "" {
 		hasAttachment = "true"
 	}
 
@@ -80,6 +118,12 @@ func (s *Session) SendMessage(msg *Message) (string, error) {
 		"signature_id":         []string{generateSignatureID()},
 	}
 
+	if msg.StickerID != "" {
+		form.Set("sticker_id", msg.StickerID)
+	}
+
+	addMentions(form, msg.Mentions)
+
 	if msg.Thread.IsGroup {
 		form.Set("thread_fbid", msg.Thread.ThreadID)
 	} else {
@@ -93,7 +137,7 @@ func (s *Session) SendMessage(msg *Message) (string, error) {
 
 	req, _ := http.NewRequest(http.MethodPost, sendMessageURL,
 		strings.NewReader(form.Encode()))
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 	req.Header.Set("Content-Type", formURLEncoded)
 
 	resp, err := s.doRequest(req)
@@ -104,15 +148,14 @@ func (s *Session) SendMessage(msg *Message) (string, error) {
 	defer resp.Body.Close()
 
 	var respMsg sendResponse
-	err = unmarshalPullData(resp.Body, &respMsg)
+	err = s.unmarshalPullData(resp.Body, &respMsg)
 	if err != nil {
 		return "", err
 	}
 
-	if respMsg.Error == loggedOutError {
-		return "", ErrLoggedOut
-	} else if respMsg.Error > 0 {
-		return "", ErrUnknown
+	err = s.responseError(respMsg.Error)
+	if err != nil {
+		return "", err
 	}
 
 	if len(respMsg.Payload.Actions) == 0 {
@@ -1,20 +1,46 @@
 package messenger
 
 import (
-	"log"
+	"context"
 	"net/http"
 	"net/http/cookiejar"
-	"os"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// requestTimeout is the timeout of the session's requests, which must be
+// longer than the chat server's long polls.
+const requestTimeout = time.Second * 70
+
 // Session represents a Facebook session.
 type Session struct {
 	client       *http.Client
 	userID       string
 	clientID     string
 	requestMutex *sync.RWMutex
+	jar          *sessionJar
+
+	// checkpointURL is the URL of the checkpoint the session must pass
+	// before it can log in.
+	checkpointURL string
+
+	profile  ClientProfile
+	profiles atomic.Pointer[profileCache]
+
+	logger        Logger
+	metrics       Metrics
+	lastRequestID atomic.Uint64
+
+	store     SessionStore
+	saveMutex *sync.Mutex
+	// loggingOut is whether the session is being or has been logged out,
+	// which prevents it from being saved so that requests finishing after
+	// the logout don't write it back to the store. It's guarded by
+	// saveMutex.
+	loggingOut bool
 
 	l    listener
 	meta meta
@@ -22,7 +48,12 @@ type Session struct {
 
 // NewSession creates a new Facebook session.
 func NewSession() *Session {
-	jar, _ := cookiejar.New(nil)
+	cookies, _ := cookiejar.New(nil)
+	jar := &sessionJar{
+		CookieJar: cookies,
+		expires:   make(map[string]time.Time),
+		mutex:     new(sync.Mutex),
+	}
 
 	return &Session{
 		client: &http.Client{
@@ -30,26 +61,84 @@ func NewSession() *Session {
 				return errNoRedirects
 			},
 			Jar:     jar,
-			Timeout: time.Second * 70,
+			Timeout: requestTimeout,
 		},
 		requestMutex: new(sync.RWMutex),
+		jar:          jar,
+		saveMutex:    new(sync.Mutex),
+		l: listener{
+			processedThreadMessages: make(map[string]*processedThread),
+			processedMutex:          new(sync.Mutex),
+			dedupSize:               defaultDedupSize,
+			stateMutex:              new(sync.Mutex),
+			closeMutex:              new(sync.Mutex),
+		},
 		meta: meta{
 			req: 1,
 		},
+		profile: DefaultClientProfile(),
+		logger:  defaultLogger(),
+		metrics: nopMetrics{},
 	}
 }
 
 func (s *Session) doRequest(req *http.Request) (resp *http.Response, err error) {
+	id := s.lastRequestID.Add(1)
+
 	s.requestMutex.RLock()
-	if os.Getenv("MDEBUG") == "true" {
-		log.Println("performing " + req.Method + " request to " + req.URL.String())
-	}
+	s.logger.Debug("performing request", "request_id", id,
+		"method", req.Method, "url", redactURL(req.URL))
 
 	resp, err = s.client.Do(req)
 
-	if os.Getenv("MDEBUG") == "true" {
-		log.Println("response code:", resp.Status)
+	if urlErr, ok := err.(*url.Error); ok {
+		urlErr.URL = redactURLString(urlErr.URL)
+	}
+
+	if err != nil {
+		s.logger.Debug("request error", "request_id", id, "error", err)
+	} else {
+		s.logger.Debug("response received", "request_id", id,
+			"status", resp.Status)
+	}
+
+	if resp != nil && resp.Body != nil {
+		resp.Body = &responseBody{ReadCloser: resp.Body, requestID: id}
 	}
 	s.requestMutex.RUnlock()
+
+	s.saveIfChanged()
 	return
 }
+
+type actionResponse struct {
+	Error int `json:"error"`
+}
+
+// postAction posts the form with the session's metadata to the URL, and
+// returns the error from the response, if any.
+func (s *Session) postAction(ctx context.Context, actionURL string,
+	form url.Values) error {
+	form = s.addFormMeta(form)
+
+	req, _ := http.NewRequest(http.MethodPost, actionURL,
+		strings.NewReader(form.Encode()))
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	var actionResp actionResponse
+	err = s.unmarshalPullData(resp.Body, &actionResp)
+	if err != nil {
+		return err
+	}
+
+	return s.responseError(actionResp.Error)
+}
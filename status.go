@@ -0,0 +1,112 @@
+package messenger
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ConnectionState represents the state of the session's connection to chat.
+type ConnectionState string
+
+// Possible connection states.
+const (
+	StateConnecting   ConnectionState = "connecting"
+	StateConnected    ConnectionState = "connected"
+	StateReconnecting ConnectionState = "reconnecting"
+	StateClosed       ConnectionState = "closed"
+)
+
+// Status represents the health of the session's connection to chat.
+type Status struct {
+	State ConnectionState `json:"state"`
+	// LastPull is the time of the last successful long poll to the chat
+	// server.
+	LastPull   time.Time `json:"last_pull"`
+	Seq        int       `json:"seq"`
+	StickyPool string    `json:"sticky_pool"`
+	// Pending is the number of events that have been dispatched to
+	// handlers which haven't returned yet.
+	Pending   int64  `json:"pending"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Status returns the current status of the session's connection to chat.
+func (s *Session) Status() Status {
+	s.l.stateMutex.Lock()
+	defer s.l.stateMutex.Unlock()
+
+	status := Status{
+		State:      s.l.connState,
+		LastPull:   s.l.lastPull,
+		Seq:        s.l.form.seq,
+		StickyPool: s.l.form.stickyPool,
+		Pending:    s.l.pending.Load(),
+	}
+
+	if status.State == "" {
+		status.State = StateClosed
+	}
+
+	if s.l.lastError != nil {
+		status.LastError = s.l.lastError.Error()
+	}
+
+	return status
+}
+
+// maxPullAge is how long after the last successful pull the status handler
+// reports the session as unavailable.
+const maxPullAge = 2 * requestTimeout
+
+// StatusHandler returns an http.Handler that serves the session's status as
+// JSON, suitable for liveness probes. It responds with 200 OK if the session
+// is connected and has successfully pulled from the chat server within the
+// last 140 seconds, and 503 Service Unavailable otherwise.
+func (s *Session) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := s.Status()
+
+		w.Header().Set("Content-Type", "application/json")
+		if status.State != StateConnected || (!status.LastPull.IsZero() &&
+			time.Since(status.LastPull) > maxPullAge) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+func (s *Session) setConnState(state ConnectionState) {
+	s.l.stateMutex.Lock()
+	s.l.connState = state
+	s.l.stateMutex.Unlock()
+}
+
+// dispatch calls the handler in a new goroutine, keeping track of the
+// number of handlers that haven't returned.
+func (s *Session) dispatch(handler func()) {
+	s.l.pending.Add(1)
+	go func() {
+		defer s.l.pending.Add(-1)
+		handler()
+	}()
+}
+
+// dispatchInOrder calls the handlers one after another in a new goroutine,
+// so that they're received in the order given.
+func (s *Session) dispatchInOrder(handlers []func()) {
+	if len(handlers) == 0 {
+		return
+	}
+
+	s.l.pending.Add(int64(len(handlers)))
+	go func() {
+		for _, handler := range handlers {
+			func() {
+				defer s.l.pending.Add(-1)
+				handler()
+			}()
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package messenger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatusHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    ConnectionState
+		lastPull time.Time
+		code     int
+	}{
+		{"closed", "", time.Time{}, http.StatusServiceUnavailable},
+		{"connecting", StateConnecting, time.Time{},
+			http.StatusServiceUnavailable},
+		{"connected", StateConnected, time.Now().Add(-time.Minute),
+			http.StatusOK},
+		{"connected without pulls", StateConnected, time.Time{},
+			http.StatusOK},
+		{"stale", StateConnected, time.Now().Add(-maxPullAge - time.Second),
+			http.StatusServiceUnavailable},
+		{"reconnecting", StateReconnecting, time.Now(),
+			http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		s := NewSession()
+		s.l.connState = test.state
+		s.l.lastPull = test.lastPull
+
+		rec := httptest.NewRecorder()
+		s.StatusHandler().ServeHTTP(rec, httptest.NewRequest(
+			http.MethodGet, "/healthz", nil))
+		if rec.Code != test.code {
+			t.Errorf("%s: got status %d, want %d", test.name, rec.Code,
+				test.code)
+		}
+	}
+}
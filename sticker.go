@@ -0,0 +1,58 @@
+package messenger
+
+import "encoding/json"
+
+// Sticker IDs of the "like" thumbs up sticker in each size, for use as
+// Message.StickerID.
+const (
+	StickerLikeSmall  = "369239263222822"
+	StickerLikeMedium = "369239343222814"
+	StickerLikeLarge  = "369239383222810"
+)
+
+// mercuryAttachment represents an attachment of a received message.
+type mercuryAttachment struct {
+	AttachType string `json:"attach_type"`
+	URL        string `json:"url"`
+	Metadata   struct {
+		StickerID flexibleID `json:"stickerID"`
+	} `json:"metadata"`
+}
+
+// deltaAttachment represents an attachment of a delta message, whose
+// details are encoded as a JSON string in mercuryJSON.
+type deltaAttachment struct {
+	MercuryJSON string `json:"mercuryJSON"`
+}
+
+func (d deltaAttachment) mercury() (mercuryAttachment, error) {
+	var attachment mercuryAttachment
+	err := json.Unmarshal([]byte(d.MercuryJSON), &attachment)
+	return attachment, err
+}
+
+// attachment converts the mercury attachment into an Attachment, returning
+// false if the type of attachment isn't supported.
+func (m mercuryAttachment) attachment() (Attachment, bool) {
+	if m.AttachType != "sticker" {
+		return Attachment{}, false
+	}
+
+	return Attachment{
+		Type:      AttachmentSticker,
+		StickerID: string(m.Metadata.StickerID),
+		URL:       m.URL,
+	}, true
+}
+
+func convertAttachments(attachments []mercuryAttachment) []Attachment {
+	var result []Attachment
+	for _, mercury := range attachments {
+		attachment, ok := mercury.attachment()
+		if ok {
+			result = append(result, attachment)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,331 @@
+package messenger
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNoSession is returned by a SessionStore's Load if there is no stored
+// session.
+var ErrNoSession = errors.New("messenger: no stored session")
+
+// SessionStore represents storage for session dumps. Once set on a session
+// with SetSessionStore, the session is saved to the store whenever its
+// cookies change and when the session is closed.
+type SessionStore interface {
+	// Load returns the stored session dump, or ErrNoSession if there is
+	// none.
+	Load() ([]byte, error)
+	// Save replaces the stored session dump with data. If data is nil, the
+	// stored session should be removed.
+	Save(data []byte) error
+}
+
+// FileStore is a SessionStore that stores the session dump in a file.
+// Writes are atomic, so a crash during a save never leaves a partially
+// written session file behind.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a FileStore that stores the session at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the session dump from the file.
+func (f *FileStore) Load() ([]byte, error) {
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoSession
+	} else if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Save atomically writes the session dump to the file, which is only
+// readable by the current user. The file is removed if data is nil.
+func (f *FileStore) Save(data []byte) error {
+	if data == nil {
+		err := os.Remove(f.path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path),
+		"."+filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), f.path)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// EncryptedStore is a SessionStore that encrypts session dumps with AES-GCM
+// before saving them to another store, in the same format as
+// DumpSessionEncrypted.
+type EncryptedStore struct {
+	store SessionStore
+	key   []byte
+}
+
+// NewEncryptedStore returns an EncryptedStore that saves to store, using the
+// key, which must be 16, 24 or 32 bytes long.
+func NewEncryptedStore(store SessionStore, key []byte) *EncryptedStore {
+	return &EncryptedStore{
+		store: store,
+		key:   append([]byte(nil), key...),
+	}
+}
+
+// Load loads and decrypts the session dump. A DecryptError is returned if
+// the key is incorrect or the stored dump isn't encrypted.
+func (e *EncryptedStore) Load() ([]byte, error) {
+	data, err := e.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptDump(e.key, data)
+}
+
+// Save encrypts and saves the session dump. The stored session is removed
+// if data is nil.
+func (e *EncryptedStore) Save(data []byte) error {
+	if data == nil {
+		return e.store.Save(nil)
+	}
+
+	encrypted, err := encryptDump(e.key, data)
+	if err != nil {
+		return err
+	}
+
+	return e.store.Save(encrypted)
+}
+
+// MemoryStore is a SessionStore that stores the session dump in memory.
+type MemoryStore struct {
+	data  []byte
+	mutex *sync.Mutex
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{mutex: new(sync.Mutex)}
+}
+
+// Load returns a copy of the stored session dump.
+func (m *MemoryStore) Load() ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.data) == 0 {
+		return nil, ErrNoSession
+	}
+
+	return append([]byte(nil), m.data...), nil
+}
+
+// Save stores a copy of the session dump.
+func (m *MemoryStore) Save(data []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.data = append([]byte(nil), data...)
+	return nil
+}
+
+// sessionJar wraps a cookie jar to keep track of whether its cookies have
+// changed since the session was last saved, and the expiry times of the
+// cookies, which cookie jars don't expose.
+type sessionJar struct {
+	http.CookieJar
+	changed bool
+	expires map[string]time.Time
+	mutex   *sync.Mutex
+}
+
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.CookieJar.Cookies(u)
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	current := make(map[string]string)
+	for _, cookie := range j.CookieJar.Cookies(u) {
+		current[cookie.Name] = cookie.Value
+	}
+
+	j.CookieJar.SetCookies(u, cookies)
+
+	now := time.Now()
+	for _, cookie := range cookies {
+		var expires time.Time
+		if cookie.MaxAge > 0 {
+			expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		} else if !cookie.Expires.IsZero() {
+			expires = cookie.Expires
+		}
+
+		// The presence cookie is regenerated before every pull, and is
+		// regenerated again after a restore, so it isn't worth saving.
+		if cookie.Name != "presence" {
+			value, ok := current[cookie.Name]
+			if cookie.MaxAge < 0 || (!expires.IsZero() && expires.Before(now)) {
+				j.changed = j.changed || ok
+			} else if !ok || value != cookie.Value ||
+				expiryChanged(j.expires[cookie.Name], expires) {
+				j.changed = true
+			}
+		}
+
+		if !expires.IsZero() {
+			j.expires[cookie.Name] = expires
+		}
+	}
+}
+
+// expiryChanged returns whether the expiry of a cookie has changed. Expiry
+// times set with Max-Age drift with the time of each response, so small
+// differences are ignored.
+func expiryChanged(previous, expires time.Time) bool {
+	if expires.IsZero() {
+		return false
+	}
+
+	diff := expires.Sub(previous)
+	return diff > time.Minute || diff < -time.Minute
+}
+
+// expiry returns the last known expiry time of the cookie with the given
+// name, or the zero time if it's unknown.
+func (j *sessionJar) expiry(name string) time.Time {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.expires[name]
+}
+
+// takeChanged returns whether the cookies have changed and resets the
+// changed state.
+func (j *sessionJar) takeChanged() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	changed := j.changed
+	j.changed = false
+	return changed
+}
+
+// SetSessionStore sets the store the session is automatically saved to.
+// Use LoadSession to restore the session from the store.
+func (s *Session) SetSessionStore(store SessionStore) {
+	s.saveMutex.Lock()
+	s.store = store
+	s.saveMutex.Unlock()
+}
+
+// LoadSession restores the session from the session store. ErrNoSession is
+// returned if the store is empty or no store has been set.
+func (s *Session) LoadSession() error {
+	s.saveMutex.Lock()
+	store := s.store
+	s.saveMutex.Unlock()
+
+	if store == nil {
+		return ErrNoSession
+	}
+
+	data, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	err = s.RestoreSession(data)
+	if err != nil {
+		return err
+	}
+
+	s.jar.takeChanged()
+	return nil
+}
+
+// saveSession saves the session to the session store, if one is set.
+func (s *Session) saveSession() error {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	if s.store == nil || s.loggingOut {
+		return nil
+	}
+
+	data, err := s.DumpSession()
+	if err != nil {
+		return err
+	}
+
+	return s.store.Save(data)
+}
+
+// saveIfChanged saves the session to the session store if its cookies have
+// changed since it was last saved.
+func (s *Session) saveIfChanged() {
+	if s.isLoggingOut() || !s.jar.takeChanged() {
+		return
+	}
+
+	err := s.saveSession()
+	if err != nil {
+		s.listenError("save session", err)
+	}
+}
+
+func (s *Session) isLoggingOut() bool {
+	s.saveMutex.Lock()
+	defer s.saveMutex.Unlock()
+
+	return s.loggingOut
+}
+
+// setLoggingOut sets whether the session is being logged out, which
+// prevents it from being saved.
+func (s *Session) setLoggingOut(loggingOut bool) {
+	s.saveMutex.Lock()
+	s.loggingOut = loggingOut
+	s.saveMutex.Unlock()
+}
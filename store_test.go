@@ -0,0 +1,108 @@
+package messenger
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestSessionJarChanged(t *testing.T) {
+	s := NewSession()
+	s.jar.SetCookies(fbURL, []*http.Cookie{
+		{Name: "c_user", Value: "1234", MaxAge: 3600},
+		{Name: "xs", Value: "token", MaxAge: 3600},
+	})
+	if !s.jar.takeChanged() {
+		t.Fatal("new cookies not marked as changed")
+	}
+
+	s.jar.SetCookies(fbURL, s.jar.Cookies(fbURL))
+	if s.jar.takeChanged() {
+		t.Error("setting the same cookies marked the jar as changed")
+	}
+
+	s.jar.SetCookies(fbURL, []*http.Cookie{
+		{Name: "xs", Value: "token", MaxAge: 3600},
+	})
+	if s.jar.takeChanged() {
+		t.Error("refreshing the same expiry marked the jar as changed")
+	}
+
+	s.jar.SetCookies(fbURL, []*http.Cookie{
+		{Name: "presence", Value: "EDvF3EtimeF1"},
+	})
+	if s.jar.takeChanged() {
+		t.Error("setting the presence cookie marked the jar as changed")
+	}
+
+	s.jar.SetCookies(fbURL, []*http.Cookie{
+		{Name: "xs", Value: "token", MaxAge: 7200},
+	})
+	if !s.jar.takeChanged() {
+		t.Error("changed expiry not marked as changed")
+	}
+
+	s.jar.SetCookies(fbURL, []*http.Cookie{
+		{Name: "xs", Value: "new", MaxAge: 7200},
+	})
+	if !s.jar.takeChanged() {
+		t.Error("changed value not marked as changed")
+	}
+
+	s.jar.SetCookies(fbURL, []*http.Cookie{
+		{Name: "xs", MaxAge: -1},
+	})
+	if !s.jar.takeChanged() {
+		t.Error("deleted cookie not marked as changed")
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	backing := NewMemoryStore()
+	key := bytes.Repeat([]byte{7}, 32)
+	store := NewEncryptedStore(backing, key)
+
+	_, err := store.Load()
+	if err != ErrNoSession {
+		t.Errorf("expected ErrNoSession, got %v", err)
+	}
+
+	s := newDumpSession()
+	s.SetSessionStore(store)
+	err = s.saveSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := backing.Load()
+	if bytes.Contains(stored, []byte("secret")) {
+		t.Error("stored session isn't encrypted")
+	}
+
+	restored := NewSession()
+	restored.SetSessionStore(store)
+	err = restored.LoadSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasCookie(restored, "xs", "secret") {
+		t.Error("cookie wasn't restored")
+	}
+
+	wrongKey := NewSession()
+	wrongKey.SetSessionStore(NewEncryptedStore(backing,
+		bytes.Repeat([]byte{8}, 32)))
+	if _, ok := wrongKey.LoadSession().(DecryptError); !ok {
+		t.Error("expected DecryptError with the wrong key")
+	}
+
+	err = store.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backing.Load(); err != ErrNoSession {
+		t.Errorf("stored session wasn't removed: %v", err)
+	}
+}
@@ -1,5 +1,14 @@
 package messenger
 
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
 // Thread represents a message thread. If IsGroup is false, ThreadID is the
 // other user's ID.
 type Thread struct {
@@ -7,4 +16,172 @@ type Thread struct {
 	IsGroup  bool
 }
 
-// TODO: Implement helper functions
+// ThreadInfo represents information about a thread. Participants is only
+// set by Session.ThreadInfo, and other fields may be missing from search
+// results.
+type ThreadInfo struct {
+	Thread         Thread
+	Name           string
+	ImageURL       string
+	ParticipantIDs []string
+	Snippet        string
+	Folder         Folder
+
+	// Participants contains the profiles of the participants, indexed by
+	// their user ID.
+	Participants map[string]UserProfile
+	AdminIDs     []string
+	// Nicknames contains the nicknames of participants, indexed by their
+	// user ID.
+	Nicknames map[string]string
+	// Color is the custom color of the thread in hex, such as "#0084ff", or
+	// empty if it's the default.
+	Color string
+	// Emoji is the custom emoji of the thread, or empty if it's the
+	// default.
+	Emoji string
+	Muted bool
+	// MutedUntil is when the thread will be unmuted. It's zero if the thread
+	// is muted indefinitely or isn't muted.
+	MutedUntil time.Time
+	// ApprovalMode is whether new members must be approved by an admin.
+	ApprovalMode bool
+	MessageCount int
+}
+
+type mercuryThread struct {
+	ThreadID     flexibleID        `json:"thread_fbid"`
+	OtherUserID  flexibleID        `json:"other_user_fbid"`
+	Name         string            `json:"name"`
+	ImageSrc     string            `json:"image_src"`
+	Participants []string          `json:"participants"`
+	Snippet      string            `json:"snippet"`
+	Folder       Folder            `json:"folder"`
+	AdminIDs     []adminID         `json:"admin_ids"`
+	Nicknames    map[string]string `json:"custom_nickname"`
+	Color        string            `json:"custom_color"`
+	Emoji        string            `json:"custom_like_icon"`
+	MuteUntil    *int64            `json:"mute_until"`
+	ApprovalMode flexibleBool      `json:"approval_mode"`
+	MessageCount int               `json:"message_count"`
+}
+
+// adminID is an admin's ID, which may be encoded as the ID itself or as an
+// object containing the ID.
+type adminID string
+
+func (a *adminID) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var admin struct {
+			ID flexibleID `json:"id"`
+		}
+		err := json.Unmarshal(data, &admin)
+		*a = adminID(admin.ID)
+		return err
+	}
+
+	var id flexibleID
+	err := json.Unmarshal(data, &id)
+	*a = adminID(id)
+	return err
+}
+
+func (m mercuryThread) info() ThreadInfo {
+	info := ThreadInfo{
+		Thread: Thread{
+			ThreadID: string(m.ThreadID),
+			IsGroup:  true,
+		},
+		Name:     m.Name,
+		ImageURL: m.ImageSrc,
+		Snippet:  m.Snippet,
+		Folder:   m.Folder,
+	}
+
+	if m.OtherUserID != "" {
+		info.Thread.ThreadID = string(m.OtherUserID)
+		info.Thread.IsGroup = false
+	}
+
+	for _, participant := range m.Participants {
+		info.ParticipantIDs = append(info.ParticipantIDs,
+			strings.TrimPrefix(participant, "fbid:"))
+	}
+
+	for _, admin := range m.AdminIDs {
+		info.AdminIDs = append(info.AdminIDs, string(admin))
+	}
+
+	info.Nicknames = m.Nicknames
+	info.Color = m.Color
+	info.Emoji = m.Emoji
+	info.ApprovalMode = bool(m.ApprovalMode)
+	info.MessageCount = m.MessageCount
+
+	// A mute_until of -1 means the thread is muted indefinitely.
+	if m.MuteUntil != nil && (*m.MuteUntil < 0 ||
+		time.Unix(*m.MuteUntil, 0).After(time.Now())) {
+		info.Muted = true
+		if *m.MuteUntil > 0 {
+			info.MutedUntil = time.Unix(*m.MuteUntil, 0)
+		}
+	}
+
+	return info
+}
+
+type threadInfoResponse struct {
+	Payload struct {
+		Threads []mercuryThread `json:"threads"`
+	} `json:"payload"`
+	Error int `json:"error"`
+}
+
+// ThreadInfo returns information about the thread, including the profiles
+// of its participants.
+func (s *Session) ThreadInfo(ctx context.Context, thread Thread) (ThreadInfo, error) {
+	form := make(url.Values)
+	form.Set("client", "mercury")
+	if thread.IsGroup {
+		form.Set("threads[thread_fbids][0]", thread.ThreadID)
+	} else {
+		form.Set("threads[user_ids][0]", thread.ThreadID)
+	}
+	form = s.addFormMeta(form)
+
+	req, _ := http.NewRequest(http.MethodPost, threadInfoURL,
+		strings.NewReader(form.Encode()))
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return ThreadInfo{}, err
+	}
+
+	defer resp.Body.Close()
+
+	var infoResp threadInfoResponse
+	err = s.unmarshalPullData(resp.Body, &infoResp)
+	if err != nil {
+		return ThreadInfo{}, err
+	}
+
+	err = s.responseError(infoResp.Error)
+	if err != nil {
+		return ThreadInfo{}, err
+	}
+
+	if len(infoResp.Payload.Threads) == 0 {
+		return ThreadInfo{}, ParseError{"could not find thread in response"}
+	}
+
+	info := infoResp.Payload.Threads[0].info()
+	info.Participants, err = s.UserProfiles(ctx, info.ParticipantIDs)
+	if err != nil {
+		return ThreadInfo{}, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,111 @@
+package messenger
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const userMessageAction = "ma-type:user-generated-message"
+
+type threadSyncResponse struct {
+	Payload struct {
+		Actions []pullAction `json:"actions"`
+	} `json:"payload"`
+	Error int `json:"error"`
+}
+
+// syncThreads fetches the messages that have been received since the last
+// delivered message and replays them in chronological order. The handlers
+// of the replayed messages are called one after another. Messages that have
+// already been delivered are suppressed by deduplication. Message requests
+// are included, and are delivered with IsMessageRequest set.
+func (s *Session) syncThreads() error {
+	s.l.stateMutex.Lock()
+	lastTimestamp := s.l.lastTimestamp
+	syncFrom := s.l.syncFrom
+	s.l.stateMutex.Unlock()
+
+	since := lastTimestamp
+	if since == 0 {
+		if syncFrom.IsZero() {
+			syncFrom = time.Now().Add(-time.Minute)
+		}
+
+		since = syncFrom.UnixNano() / 1e6
+	}
+
+	form := make(url.Values)
+	form.Set("client", "mercury")
+	form.Set("folders[0]", string(FolderInbox))
+	form.Set("folders[1]", string(FolderPending))
+	form.Set("last_action_timestamp", strconv.FormatInt(since, 10))
+	form = s.addFormMeta(form)
+
+	req, _ := http.NewRequest(http.MethodPost, threadSyncURL,
+		strings.NewReader(form.Encode()))
+	req.Header = s.defaultHeader()
+	req.Header.Set("Content-Type", formURLEncoded)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	var syncResp threadSyncResponse
+	err = s.unmarshalPullData(resp.Body, &syncResp)
+	if err != nil {
+		return err
+	}
+
+	err = s.responseError(syncResp.Error)
+	if err != nil {
+		return err
+	}
+
+	actions := syncResp.Payload.Actions
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Timestamp < actions[j].Timestamp
+	})
+
+	var handlers []func()
+	for _, action := range actions {
+		if action.ActionType != userMessageAction ||
+			action.Timestamp <= lastTimestamp {
+			continue
+		}
+
+		handler := s.handleDeltaMessage(action.Body, action.metadata(),
+			convertAttachments(action.Attachments),
+			convertProfileRanges(action.ProfileRanges))
+		if handler != nil {
+			handlers = append(handlers, handler)
+		}
+	}
+
+	s.dispatchInOrder(handlers)
+
+	return nil
+}
+
+// metadata converts the action into the metadata of an equivalent delta
+// message.
+func (a pullAction) metadata() pullMsgMeta {
+	var meta pullMsgMeta
+	meta.Sender = strings.TrimPrefix(a.Author, "fbid:")
+	if a.OtherUserID != "" {
+		meta.ThreadKey.OtherUserID = a.OtherUserID
+	} else {
+		meta.ThreadKey.ThreadID = a.ThreadID
+	}
+	meta.MessageID = a.MessageID
+	meta.OfflineThreadID = a.OfflineThreadID
+	meta.Timestamp = strconv.FormatInt(a.Timestamp, 10)
+	meta.FolderID.SystemFolderID = string(a.Folder)
+	return meta
+}
@@ -0,0 +1,123 @@
+package messenger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncThreads(t *testing.T) {
+	s, replayer := newReplaySession(t, "testdata/thread_sync.jsonl")
+	s.l.lastTimestamp = 1500000000000
+
+	messages := make(chan *Message, 4)
+	s.OnMessage(func(msg *Message) {
+		// Give out of order deliveries a chance to overtake.
+		time.Sleep(10 * time.Millisecond)
+		messages <- msg
+	})
+	s.checkListeners()
+
+	err := s.syncThreads()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		body             string
+		thread           Thread
+		isMessageRequest bool
+	}{
+		{"first", Thread{ThreadID: "2000", IsGroup: true}, false},
+		{"second", Thread{ThreadID: "1002"}, false},
+		{"third", Thread{ThreadID: "1003"}, true},
+	}
+
+	for _, w := range want {
+		select {
+		case msg := <-messages:
+			if msg.Body != w.body || msg.Thread != w.thread ||
+				msg.IsMessageRequest != w.isMessageRequest {
+				t.Errorf("got message %+v, want %+v", msg, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for message %q", w.body)
+		}
+	}
+
+	select {
+	case msg := <-messages:
+		t.Errorf("unexpected message: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if s.ListenerState().LastTimestamp != 1500000003000 {
+		t.Errorf("unexpected last timestamp: %d",
+			s.ListenerState().LastTimestamp)
+	}
+
+	if replayer.Remaining() != 0 {
+		t.Errorf("%d interactions weren't replayed", replayer.Remaining())
+	}
+}
+
+// formTransport records the form of each request and responds with an empty
+// thread sync.
+type formTransport struct {
+	forms []url.Values
+}
+
+func (f *formTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, _ := ioutil.ReadAll(req.Body)
+	form, _ := url.ParseQuery(string(data))
+	f.forms = append(f.forms, form)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body: ioutil.NopCloser(strings.NewReader(
+			`for (;;); {"payload":{"actions":[]}}`)),
+		Request: req,
+	}, nil
+}
+
+func TestSyncThreadsSince(t *testing.T) {
+	s := NewSession()
+	transport := new(formTransport)
+	s.SetTransport(transport)
+	s.SetLogger(nil)
+
+	syncFrom := time.Now().Add(-10 * time.Minute)
+	s.l.syncFrom = syncFrom
+
+	err := s.syncThreads()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.l.lastTimestamp = 1500000000000
+	err = s.syncThreads()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		strconv.FormatInt(syncFrom.UnixNano()/1e6, 10),
+		"1500000000000",
+	}
+
+	for i, form := range transport.forms {
+		if since := form.Get("last_action_timestamp"); since != want[i] {
+			t.Errorf("sync %d: got last_action_timestamp %s, want %s", i,
+				since, want[i])
+		}
+
+		if form.Get("folders[1]") != string(FolderPending) {
+			t.Errorf("sync %d: pending folder not requested", i)
+		}
+	}
+}
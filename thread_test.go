@@ -0,0 +1,41 @@
+package messenger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAdminID(t *testing.T) {
+	tests := []struct {
+		data string
+		want []adminID
+		err  bool
+	}{
+		{`[]`, []adminID{}, false},
+		{`["1001",1002]`, []adminID{"1001", "1002"}, false},
+		{`[{"id":"1001"},{"id":1002}]`, []adminID{"1001", "1002"}, false},
+		{`[{"id":null}]`, []adminID{""}, false},
+		{`[{"id":true}]`, nil, true},
+		{`[false]`, nil, true},
+	}
+
+	for _, test := range tests {
+		var ids []adminID
+		err := json.Unmarshal([]byte(test.data), &ids)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.data)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.data, err)
+		}
+
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("%s: got %q, want %q", test.data, ids, test.want)
+		}
+	}
+}
@@ -29,7 +29,7 @@ func (s *Session) SetTypingIndicator(thread Thread, typing bool) error {
 
 	req, _ := http.NewRequest(http.MethodPost, typingURL,
 		strings.NewReader(form.Encode()))
-	req.Header = defaultHeader()
+	req.Header = s.defaultHeader()
 	req.Header.Set("Content-Type", formURLEncoded)
 
 	resp, err := s.doRequest(req)
@@ -0,0 +1,111 @@
+package messenger
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SessionState represents the login state of a session.
+type SessionState int
+
+// Possible session states returned by Validate.
+const (
+	SessionOK SessionState = iota
+	SessionLoggedOut
+	SessionCheckpoint
+)
+
+func (s SessionState) String() string {
+	switch s {
+	case SessionOK:
+		return "ok"
+	case SessionLoggedOut:
+		return "logged out"
+	case SessionCheckpoint:
+		return "checkpoint"
+	}
+
+	return "unknown"
+}
+
+// SessionInfo represents information about the validity of a session.
+type SessionInfo struct {
+	State  SessionState
+	UserID string
+	// Expires is the earliest expiry time of the session's login cookies. It
+	// is zero if the expiry is unknown, such as for sessions restored from
+	// legacy session dumps.
+	Expires time.Time
+}
+
+// loginCookies are the cookies that must be present for a session to be
+// logged in.
+var loginCookies = []string{"c_user", "xs"}
+
+// Validate checks whether the session is logged in without connecting to
+// chat. An error is only returned if the login state could not be
+// determined.
+func (s *Session) Validate(ctx context.Context) (SessionInfo, error) {
+	info := SessionInfo{State: SessionLoggedOut}
+
+	for _, cookie := range s.client.Jar.Cookies(fbURL) {
+		if cookie.Name == "c_user" {
+			info.UserID = cookie.Value
+		}
+	}
+
+	if info.UserID == "" {
+		return info, nil
+	}
+
+	for _, name := range loginCookies {
+		expires := s.jar.expiry(name)
+		if !expires.IsZero() && (info.Expires.IsZero() ||
+			expires.Before(info.Expires)) {
+			info.Expires = expires
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, facebookURL, nil)
+	req.Header = s.defaultHeader()
+	req = req.WithContext(ctx)
+
+	resp, err := s.doRequest(req)
+	if err != nil {
+		urlErr, ok := err.(*url.Error)
+		if !ok || urlErr.Err != errNoRedirects {
+			return SessionInfo{}, err
+		}
+
+		redirURL, err := resp.Location()
+		if err != nil {
+			return SessionInfo{}, err
+		}
+
+		if strings.Contains(redirURL.String(), "https://www.facebook.com/checkpoint") {
+			info.State = SessionCheckpoint
+		}
+
+		return info, nil
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	if bytes.Contains(data, securityCheckHeader) {
+		info.State = SessionCheckpoint
+	} else if bytes.Contains(data, []byte("name=\"fb_dtsg\"")) {
+		info.State = SessionOK
+	}
+
+	return info, nil
+}